@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/v2fly/domain-list-community/internal/dlc"
+)
+
+// AdblockDialect is the filter syntax of an adblocker.
+type AdblockDialect struct {
+	Name string
+	// DNS is whether the filters match hostnames like AdGuard Home, otherwise
+	// URLs like uBlock Origin and Adblock Plus
+	DNS bool
+}
+
+var (
+	DialectAdGuard = &AdblockDialect{Name: "adguard", DNS: true}
+	DialectAdblock = &AdblockDialect{Name: "adblock"}
+)
+
+// filters returns the filters of the entries. Keyword and regexp rules
+// become regexp filters, which match the URLs whose host is matched by the
+// rule in the URL dialect.
+func (d *AdblockDialect) filters(entries []*Entry) ([]string, []*Entry) {
+	filters := make([]string, 0, len(entries))
+	var dropped []*Entry
+	for _, entry := range entries {
+		switch entry.Type {
+		case dlc.RuleTypeDomain:
+			filters = append(filters, "||"+entry.Value+"^")
+		case dlc.RuleTypeFullDomain:
+			if d.DNS {
+				filters = append(filters, "|"+entry.Value+"^")
+			} else {
+				filters = append(filters, "|http://"+entry.Value+"^", "|https://"+entry.Value+"^")
+			}
+		case dlc.RuleTypeKeyword, dlc.RuleTypeRegexp:
+			expr := entry.Value
+			if entry.Type == dlc.RuleTypeKeyword {
+				expr = regexp.QuoteMeta(expr)
+			}
+			if !d.DNS {
+				hr, ok := parseHostRegexp(expr)
+				if !ok {
+					dropped = append(dropped, entry)
+					continue
+				}
+				expr = hr.urlRegexp()
+			}
+			// Slashes delimit regexp filters
+			filters = append(filters, "/"+strings.ReplaceAll(expr, "/", `\/`)+"/")
+		}
+	}
+	return filters, dropped
+}
+
+// write returns the function writing lists as a filter list of the dialect,
+// with the metadata of the list in the header.
+func (d *AdblockDialect) write() func(io.Writer, []*ExportList, ExportOptions) error {
+	return func(w io.Writer, lists []*ExportList, opts ExportOptions) error {
+		built, err := buildTime()
+		if err != nil {
+			return err
+		}
+		names := make([]string, len(lists))
+		for i, list := range lists {
+			names[i] = list.Name
+		}
+		header := [][2]string{
+			{"Title", opts.get("title", "domain-list-community "+strings.Join(names, ","))},
+			{"Version", opts.get("version", built.Format("200601021504"))},
+			{"Expires", opts.get("expires", "1 day")},
+			{"Last modified", built.Format("2006-01-02T15:04:05Z")},
+			{"Homepage", generatorHomepage},
+		}
+		for _, field := range header {
+			if field[1] == "" || strings.ContainsAny(field[1], "\r\n") {
+				return fmt.Errorf("invalid %s of %s filter list: %q", strings.ToLower(field[0]), d.Name, field[1])
+			}
+		}
+
+		filters, dropped := d.filters(stripAttrs(mergeEntries(lists)))
+		warnDropped(d.Name, lists, dropped)
+		if !d.DNS {
+			fmt.Fprintln(w, "[Adblock Plus 2.0]")
+		}
+		for _, field := range header {
+			fmt.Fprintf(w, "! %s: %s\n", field[0], field[1])
+		}
+		for _, filter := range filters {
+			fmt.Fprintln(w, filter)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+)
+
+func TestAdblockDialects(t *testing.T) {
+	lists := []*ExportList{
+		testExportList(t, "category-ads",
+			"domain:example.com @ads",
+			"full:ads.example.org",
+			"keyword:tracker",
+			`regexp:^ads\d+\.example\.net$`,
+			`regexp:^a/b$`,
+			`regexp:^x\b`, // Dropped by URL dialect
+		),
+	}
+	testCases := []struct {
+		dialect *AdblockDialect
+		want    string
+	}{
+		{
+			dialect: DialectAdGuard,
+			want: "! Title: ads\n" +
+				"! Version: 202601020304\n" +
+				"! Expires: 1 day\n" +
+				"! Last modified: 2026-01-02T03:04:05Z\n" +
+				"! Homepage: https://github.com/v2fly/domain-list-community\n" +
+				"||example.com^\n" +
+				"|ads.example.org^\n" +
+				"/tracker/\n" +
+				`/^a\/b$/` + "\n" +
+				`/^ads\d+\.example\.net$/` + "\n" +
+				`/^x\b/` + "\n",
+		},
+		{
+			dialect: DialectAdblock,
+			want: "[Adblock Plus 2.0]\n" +
+				"! Title: ads\n" +
+				"! Version: 202601020304\n" +
+				"! Expires: 1 day\n" +
+				"! Last modified: 2026-01-02T03:04:05Z\n" +
+				"! Homepage: https://github.com/v2fly/domain-list-community\n" +
+				"||example.com^\n" +
+				"|http://ads.example.org^\n" +
+				"|https://ads.example.org^\n" +
+				`/^https?:\/\/[-.0-9A-Z_a-z]*tracker/` + "\n" +
+				`/^https?:\/\/a\/b(?::\d+)?(?:[\/?#]|$)/` + "\n" +
+				`/^https?:\/\/ads[0-9]+\.example\.net(?::\d+)?(?:[\/?#]|$)/` + "\n",
+		},
+	}
+	t.Setenv("SOURCE_DATE_EPOCH", "1767323045") // 2026-01-02T03:04:05Z
+	for _, tc := range testCases {
+		var buf bytes.Buffer
+		if err := tc.dialect.write()(&buf, lists, ExportOptions{"title": "ads"}); err != nil {
+			t.Fatalf("%s got unexpected error: %v", tc.dialect.Name, err)
+		}
+		if got := buf.String(); got != tc.want {
+			t.Errorf("%s = %q, want %q", tc.dialect.Name, got, tc.want)
+		}
+	}
+
+	if err := DialectAdGuard.write()(new(bytes.Buffer), lists, ExportOptions{"expires": "1 day\n||x^"}); err == nil {
+		t.Error("adguard with multi-line expires = nil error, want invalid expires error")
+	}
+	t.Setenv("SOURCE_DATE_EPOCH", "now")
+	if err := DialectAdGuard.write()(new(bytes.Buffer), lists, nil); err == nil {
+		t.Error("adguard with invalid SOURCE_DATE_EPOCH = nil error, want error")
+	}
+}
+
+// TestAdblockURLRegexp checks the URL regexp filters, which are JavaScript
+// regexps, against URLs as Go regexps of the same semantics.
+func TestAdblockURLRegexp(t *testing.T) {
+	filters, _ := DialectAdblock.filters(testExportList(t, "ads", "keyword:tracker").Entries)
+	re := regexp.MustCompile(filters[0][1 : len(filters[0])-1])
+	for url, want := range map[string]bool{
+		"https://a.tracker.example.com/":  true,
+		"http://trackers.example.com":     true,
+		"https://example.com/tracker":     false,
+		"https://example.com/?q=.tracker": false,
+	} {
+		if got := re.MatchString(url); got != want {
+			t.Errorf("%s matches %q = %v, want %v", filters[0], url, got, want)
+		}
+	}
+}
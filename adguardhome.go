@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const defaultAdGuardHomeLineLength = 1024
+
+// writeAdGuardHome writes the lists as AdGuard Home upstreams like
+// `[/example.com/example.org/]tls://1.1.1.1`, with the upstreams of the
+// `upstream` option of each list, separated by spaces. The domains of a list
+// are chunked into lines of at most `linelength` bytes, unless a domain is
+// longer than that by itself. The first list of a domain wins.
+func writeAdGuardHome(w io.Writer, lists []*ExportList, opts ExportOptions) error {
+	lineLength, err := strconv.Atoi(opts.get("linelength", strconv.Itoa(defaultAdGuardHomeLineLength)))
+	if err != nil || lineLength <= 0 {
+		return fmt.Errorf("invalid line length: %q", opts.get("linelength", ""))
+	}
+	seen := make(map[string]bool)
+	for _, list := range lists {
+		upstream := strings.Join(strings.Fields(opts.getFor("upstream", list.Name, "")), " ")
+		if upstream == "" || strings.ContainsAny(upstream, "[]") {
+			return fmt.Errorf("invalid upstream %q of list %q", upstream, list.Name)
+		}
+		var line strings.Builder
+		flush := func() {
+			if line.Len() != 0 {
+				fmt.Fprintf(w, "[%s/]%s\n", line.String(), upstream)
+				line.Reset()
+			}
+		}
+		for _, domain := range suffixDomains("adguardhome", []*ExportList{list}, false) {
+			if seen[domain] {
+				continue
+			}
+			seen[domain] = true
+			// The length of the line with the domain, its slash, the brackets,
+			// the last slash and the upstream
+			if line.Len() != 0 && line.Len()+len(domain)+len(upstream)+4 > lineLength {
+				flush()
+			}
+			line.WriteByte('/')
+			line.WriteString(domain)
+		}
+		flush()
+	}
+	return nil
+}
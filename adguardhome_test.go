@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteAdGuardHome(t *testing.T) {
+	lists := []*ExportList{
+		testExportList(t, "cn",
+			"domain:a.cn",
+			"domain:bb.cn",
+			"domain:ccc.cn",
+			"full:d.example.com",
+		),
+		testExportList(t, "gfw",
+			"domain:a.cn", // Duplicated
+			"domain:example.org",
+		),
+	}
+	testCases := []struct {
+		opts ExportOptions
+		want string
+	}{
+		{
+			opts: ExportOptions{"upstream": "tls://1.1.1.1", "upstream:cn": "114.114.114.114  223.5.5.5"},
+			want: "[/a.cn/bb.cn/ccc.cn/d.example.com/]114.114.114.114 223.5.5.5\n" +
+				"[/example.org/]tls://1.1.1.1\n",
+		},
+		{
+			// "[/a.cn/bb.cn/]1.1.1.1" is 21 bytes long
+			opts: ExportOptions{"upstream": "1.1.1.1", "linelength": "21"},
+			want: "[/a.cn/bb.cn/]1.1.1.1\n" +
+				"[/ccc.cn/]1.1.1.1\n" +
+				"[/d.example.com/]1.1.1.1\n" +
+				"[/example.org/]1.1.1.1\n",
+		},
+	}
+	for _, tc := range testCases {
+		var buf bytes.Buffer
+		if err := writeAdGuardHome(&buf, lists, tc.opts); err != nil {
+			t.Fatalf("writeAdGuardHome(%v) got unexpected error: %v", tc.opts, err)
+		}
+		if got := buf.String(); got != tc.want {
+			t.Errorf("writeAdGuardHome(%v) = %q, want %q", tc.opts, got, tc.want)
+		}
+	}
+
+	for _, opts := range []ExportOptions{
+		{"upstream:cn": "1.1.1.1"},
+		{"upstream": "1.1.1.1", "linelength": "0"},
+	} {
+		if err := writeAdGuardHome(new(bytes.Buffer), lists, opts); err == nil {
+			t.Errorf("writeAdGuardHome(%v) = nil error, want error", opts)
+		}
+	}
+}
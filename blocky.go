@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/v2fly/domain-list-community/internal/dlc"
+)
+
+// writeBlocky writes the lists as a Blocky allowlist or denylist file, with
+// `*.example.com` for domain type rules, and Go regexps like `/regexp/` for
+// keyword and regexp rules. Full type rules also match their subdomains in
+// Blocky.
+func writeBlocky(w io.Writer, lists []*ExportList, _ ExportOptions) error {
+	var approximated []*Entry
+	for _, entry := range stripAttrs(mergeEntries(lists)) {
+		switch entry.Type {
+		case dlc.RuleTypeDomain:
+			fmt.Fprintln(w, "*."+entry.Value)
+		case dlc.RuleTypeFullDomain:
+			approximated = append(approximated, entry)
+			fmt.Fprintln(w, entry.Value)
+		case dlc.RuleTypeKeyword:
+			fmt.Fprintf(w, "/%s/\n", regexp.QuoteMeta(entry.Value))
+		case dlc.RuleTypeRegexp:
+			fmt.Fprintf(w, "/%s/\n", entry.Value)
+		}
+	}
+	warnApproximated("blocky", lists, approximated)
+	return nil
+}
+
+// writeBlockyConditional writes the lists as the `conditional` section of a
+// Blocky config, whose mapping routes every domain with its subdomains to
+// the `upstream` of its list. The first list of a domain wins.
+func writeBlockyConditional(w io.Writer, lists []*ExportList, opts ExportOptions) error {
+	fmt.Fprint(w, "conditional:\n  mapping:\n")
+	seen := make(map[string]bool)
+	for _, list := range lists {
+		upstream := opts.getFor("upstream", list.Name, "")
+		if upstream == "" || strings.ContainsAny(upstream, "\r\n") {
+			return fmt.Errorf("invalid upstream %q of list %q", upstream, list.Name)
+		}
+		for _, domain := range suffixDomains("blocky-conditional", []*ExportList{list}, false) {
+			if !seen[domain] {
+				seen[domain] = true
+				fmt.Fprintf(w, "    %s: '%s'\n", domain, strings.ReplaceAll(upstream, "'", "''"))
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteBlocky(t *testing.T) {
+	lists := []*ExportList{
+		testExportList(t, "category-ads",
+			"domain:example.com",
+			"full:ads.example.org",
+			"keyword:track.er",
+			`regexp:^ads\d+\.example\.net$`,
+		),
+		testExportList(t, "cn", "domain:example.cn"),
+	}
+	var buf bytes.Buffer
+	if err := writeBlocky(&buf, lists[:1], nil); err != nil {
+		t.Fatalf("writeBlocky got unexpected error: %v", err)
+	}
+	want := "*.example.com\n" +
+		"ads.example.org\n" +
+		"/track\\.er/\n" +
+		"/^ads\\d+\\.example\\.net$/\n"
+	if got := buf.String(); got != want {
+		t.Errorf("writeBlocky() = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	opts := ExportOptions{"upstream": "tcp-tls:1.1.1.1:853", "upstream:cn": "114.114.114.114,223.5.5.5"}
+	if err := writeBlockyConditional(&buf, lists, opts); err != nil {
+		t.Fatalf("writeBlockyConditional got unexpected error: %v", err)
+	}
+	want = `conditional:
+  mapping:
+    example.com: 'tcp-tls:1.1.1.1:853'
+    ads.example.org: 'tcp-tls:1.1.1.1:853'
+    example.cn: '114.114.114.114,223.5.5.5'
+`
+	if got := buf.String(); got != want {
+		t.Errorf("writeBlockyConditional() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/v2fly/domain-list-community/internal/dlc"
+	"github.com/v2fly/domain-list-community/internal/plainyml"
+	router "github.com/v2fly/v2ray-core/v5/app/router/routercommon"
+	"google.golang.org/protobuf/proto"
+)
+
+const deltaVersion = 1
+
+// Delta is the list-level difference between an old and a new dat file. The
+// sites of the new file are the sites of the old file without RemovedSites,
+// with the rules of each site in Sites patched, and the sites in Sites with At
+// inserted at their indexes in the new file. Rules are written like the ones
+// of the plaintext lists, e.g. `full:www.example.com:@ads,@cn`.
+type Delta struct {
+	Version      int          `json:"version"`
+	Old          *DeltaFile   `json:"old"`
+	New          *DeltaFile   `json:"new"`
+	RemovedSites []string     `json:"removed_sites,omitempty"`
+	Sites        []*SiteDelta `json:"sites,omitempty"`
+}
+
+// DeltaFile identifies the dat file which a delta applies to or results in.
+type DeltaFile struct {
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// SiteDelta patches the rules of a site, by removing the rules at the indexes
+// in Removed from the old rules, and inserting the rules of Added at their
+// indexes in the new rules. A new site has At, which is its index in the new
+// file, and only added rules.
+type SiteDelta struct {
+	Name    string     `json:"name"`
+	At      *int       `json:"at,omitempty"`
+	Removed []int      `json:"removed,omitempty"`
+	Added   []*RuleAdd `json:"added,omitempty"`
+}
+
+type RuleAdd struct {
+	At   int    `json:"at"`
+	Rule string `json:"rule"`
+}
+
+func newDeltaFile(data []byte) *DeltaFile {
+	sum := sha256.Sum256(data)
+	return &DeltaFile{Size: len(data), SHA256: hex.EncodeToString(sum[:])}
+}
+
+func (f *DeltaFile) check(data []byte, what string) error {
+	if got := newDeltaFile(data); *got != *f {
+		return fmt.Errorf("%s file has size %d and sha256 %s, want %d and %s", what, got.Size, got.SHA256, f.Size, f.SHA256)
+	}
+	return nil
+}
+
+// formatRule returns the rule of the domain in the plaintext format of
+// plainyml, like datdump writes. Only boolean attributes, which are the ones
+// generated by this repository, can be expressed.
+func formatRule(d *router.Domain) (string, error) {
+	if strings.Contains(d.Value, ":@") {
+		return "", fmt.Errorf("unsupported value of rule %q", d.Value)
+	}
+	for _, attr := range d.Attribute {
+		if !attr.GetBoolValue() || strings.ContainsAny(attr.Key, ",:@") {
+			return "", fmt.Errorf("unsupported attribute %q of rule %q", attr.Key, d.Value)
+		}
+	}
+	var b strings.Builder
+	if err := plainyml.WriteRule(&b, d); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// parseRule parses the rule in the plaintext format written by formatRule.
+func parseRule(rule string) (*router.Domain, error) {
+	typ, rest, ok := strings.Cut(rule, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid rule: %q", rule)
+	}
+	d := new(router.Domain)
+	switch typ {
+	case dlc.RuleTypeDomain:
+		d.Type = router.Domain_RootDomain
+	case dlc.RuleTypeFullDomain:
+		d.Type = router.Domain_Full
+	case dlc.RuleTypeKeyword:
+		d.Type = router.Domain_Plain
+	case dlc.RuleTypeRegexp:
+		d.Type = router.Domain_Regex
+	default:
+		return nil, fmt.Errorf("invalid rule type of rule %q", rule)
+	}
+	// Attributes follow the last ":@", as regexps may contain ':'
+	d.Value = rest
+	if i := strings.LastIndex(rest, ":@"); i >= 0 {
+		d.Value = rest[:i]
+		for attr := range strings.SplitSeq(rest[i+1:], ",") {
+			key, ok := strings.CutPrefix(attr, "@")
+			if !ok || key == "" {
+				return nil, fmt.Errorf("invalid attribute of rule %q", rule)
+			}
+			d.Attribute = append(d.Attribute, &router.Domain_Attribute{
+				Key:        key,
+				TypedValue: &router.Domain_Attribute_BoolValue{BoolValue: true},
+			})
+		}
+	}
+	return d, nil
+}
+
+// siteRules returns the rules of the site in the plaintext format, or an error
+// if the site cannot be reconstructed from them.
+func siteRules(site *router.GeoSite) ([]string, error) {
+	if len(site.ResourceHash) != 0 || site.Code != "" || site.FilePath != "" {
+		return nil, fmt.Errorf("site %q has unsupported fields", site.CountryCode)
+	}
+	rules := make([]string, len(site.Domain))
+	for i, d := range site.Domain {
+		var err error
+		if rules[i], err = formatRule(d); err != nil {
+			return nil, fmt.Errorf("site %q: %w", site.CountryCode, err)
+		}
+	}
+	return rules, nil
+}
+
+// diffSeq returns the indexes of the old items to remove, and the indexes of
+// the new items to insert, which keep the common items in the same order. It
+// keeps every item found later in the old items, which is minimal for sorted
+// items like the rules generated by this repository.
+func diffSeq(oldItems, newItems []string) (removed, added []int) {
+	positions := make(map[string][]int, len(oldItems))
+	for i, item := range oldItems {
+		positions[item] = append(positions[item], i)
+	}
+	next := 0
+	for i, item := range newItems {
+		idxes := positions[item]
+		k := sort.SearchInts(idxes, next)
+		if k == len(idxes) {
+			added = append(added, i)
+			continue
+		}
+		for j := next; j < idxes[k]; j++ {
+			removed = append(removed, j)
+		}
+		next = idxes[k] + 1
+	}
+	for j := next; j < len(oldItems); j++ {
+		removed = append(removed, j)
+	}
+	return removed, added
+}
+
+// patchSeq applies the indexes returned by diffSeq to the old items, where the
+// inserted items are returned by the insert function.
+func patchSeq[T any](oldItems []T, removed []int, added []int, insert func(i int) T) ([]T, error) {
+	items := make([]T, 0, len(oldItems)-len(removed)+len(added))
+	for i, item := range oldItems {
+		if len(removed) > 0 && removed[0] == i {
+			removed = removed[1:]
+			continue
+		}
+		items = append(items, item)
+	}
+	if len(removed) > 0 {
+		return nil, fmt.Errorf("removed index %d out of range", removed[0])
+	}
+	for i, at := range added {
+		if at < 0 || at > len(items) || (i > 0 && at <= added[i-1]) {
+			return nil, fmt.Errorf("invalid added index %d", at)
+		}
+		items = slices.Insert(items, at, insert(i))
+	}
+	return items, nil
+}
+
+func unmarshalDat(data []byte) ([]*router.GeoSite, error) {
+	geoSiteList := new(router.GeoSiteList)
+	if err := proto.Unmarshal(data, geoSiteList); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal: %w", err)
+	}
+	return geoSiteList.Entry, nil
+}
+
+// Diff returns the delta from the old dat file to the new one, which is
+// verified to reconstruct the new file.
+func Diff(oldData, newData []byte) (*Delta, error) {
+	oldSites, err := unmarshalDat(oldData)
+	if err != nil {
+		return nil, fmt.Errorf("old file: %w", err)
+	}
+	newSites, err := unmarshalDat(newData)
+	if err != nil {
+		return nil, fmt.Errorf("new file: %w", err)
+	}
+	oldNames := make([]string, len(oldSites))
+	oldByName := make(map[string]*router.GeoSite, len(oldSites))
+	for i, site := range oldSites {
+		oldNames[i] = site.CountryCode
+		oldByName[site.CountryCode] = site
+	}
+	newNames := make([]string, len(newSites))
+	for i, site := range newSites {
+		newNames[i] = site.CountryCode
+	}
+	if len(oldByName) != len(oldSites) {
+		return nil, fmt.Errorf("old file has duplicated sites")
+	}
+	if len(slices.Compact(slices.Sorted(slices.Values(newNames)))) != len(newNames) {
+		return nil, fmt.Errorf("new file has duplicated sites")
+	}
+
+	delta := &Delta{Version: deltaVersion, Old: newDeltaFile(oldData), New: newDeltaFile(newData)}
+	removedSites, addedSites := diffSeq(oldNames, newNames)
+	for _, i := range removedSites {
+		delta.RemovedSites = append(delta.RemovedSites, oldNames[i])
+	}
+	isAdded := make(map[int]bool, len(addedSites))
+	for _, i := range addedSites {
+		isAdded[i] = true
+	}
+	for i, site := range newSites {
+		newRules, err := siteRules(site)
+		if err != nil {
+			return nil, fmt.Errorf("new file: %w", err)
+		}
+		var oldRules []string
+		sd := &SiteDelta{Name: site.CountryCode}
+		if isAdded[i] {
+			sd.At = &i
+		} else if oldRules, err = siteRules(oldByName[site.CountryCode]); err != nil {
+			return nil, fmt.Errorf("old file: %w", err)
+		}
+		var added []int
+		sd.Removed, added = diffSeq(oldRules, newRules)
+		for _, at := range added {
+			sd.Added = append(sd.Added, &RuleAdd{At: at, Rule: newRules[at]})
+		}
+		if sd.At != nil || len(sd.Removed) != 0 || len(sd.Added) != 0 {
+			delta.Sites = append(delta.Sites, sd)
+		}
+	}
+
+	// Make sure the delta reconstructs the new file
+	if _, err := Apply(oldData, delta); err != nil {
+		return nil, fmt.Errorf("failed to verify delta: %w", err)
+	}
+	return delta, nil
+}
+
+// Apply returns the new dat file reconstructed from the old one by the delta,
+// after checking the sizes and checksums of both files.
+func Apply(oldData []byte, delta *Delta) ([]byte, error) {
+	if delta.Version != deltaVersion {
+		return nil, fmt.Errorf("unsupported delta version %d", delta.Version)
+	}
+	if delta.Old == nil || delta.New == nil {
+		return nil, fmt.Errorf("delta has no checksums")
+	}
+	if err := delta.Old.check(oldData, "old"); err != nil {
+		return nil, err
+	}
+	sites, err := unmarshalDat(oldData)
+	if err != nil {
+		return nil, fmt.Errorf("old file: %w", err)
+	}
+
+	var removedSites []int
+	for i, site := range sites {
+		if slices.Contains(delta.RemovedSites, site.CountryCode) {
+			removedSites = append(removedSites, i)
+		}
+	}
+	if len(removedSites) != len(delta.RemovedSites) {
+		return nil, fmt.Errorf("removed sites not found in old file")
+	}
+	var addedSites []*SiteDelta
+	patches := make(map[string]*SiteDelta)
+	for _, sd := range delta.Sites {
+		if sd.At != nil {
+			addedSites = append(addedSites, sd)
+		} else {
+			patches[sd.Name] = sd
+		}
+	}
+	addedIdxes := make([]int, len(addedSites))
+	for i, sd := range addedSites {
+		addedIdxes[i] = *sd.At
+	}
+	sites, err = patchSeq(sites, removedSites, addedIdxes, func(i int) *router.GeoSite {
+		return &router.GeoSite{CountryCode: addedSites[i].Name}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to patch sites: %w", err)
+	}
+
+	for _, sd := range addedSites {
+		patches[sd.Name] = sd
+	}
+	for i, site := range sites {
+		sd, ok := patches[site.CountryCode]
+		if !ok {
+			continue
+		}
+		delete(patches, site.CountryCode)
+		added := make([]int, len(sd.Added))
+		domains := make([]*router.Domain, len(sd.Added))
+		for j, ra := range sd.Added {
+			added[j] = ra.At
+			if domains[j], err = parseRule(ra.Rule); err != nil {
+				return nil, fmt.Errorf("site %q: %w", sd.Name, err)
+			}
+		}
+		patched, err := patchSeq(site.Domain, sd.Removed, added, func(j int) *router.Domain { return domains[j] })
+		if err != nil {
+			return nil, fmt.Errorf("failed to patch site %q: %w", sd.Name, err)
+		}
+		sites[i] = &router.GeoSite{CountryCode: site.CountryCode, Domain: patched}
+	}
+	if len(patches) != 0 {
+		return nil, fmt.Errorf("%d patched site(s) not found in old file", len(patches))
+	}
+
+	newData, err := proto.Marshal(&router.GeoSiteList{Entry: sites})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal: %w", err)
+	}
+	if err := delta.New.check(newData, "reconstructed"); err != nil {
+		return nil, err
+	}
+	return newData, nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+
+	router "github.com/v2fly/v2ray-core/v5/app/router/routercommon"
+	"google.golang.org/protobuf/proto"
+)
+
+func testDat(t *testing.T, sites map[string][]string, order ...string) []byte {
+	t.Helper()
+	geoSiteList := new(router.GeoSiteList)
+	for _, name := range order {
+		site := &router.GeoSite{CountryCode: name}
+		for _, rule := range sites[name] {
+			d, err := parseRule(rule)
+			if err != nil {
+				t.Fatalf("parseRule(%q) got unexpected error: %v", rule, err)
+			}
+			site.Domain = append(site.Domain, d)
+		}
+		geoSiteList.Entry = append(geoSiteList.Entry, site)
+	}
+	data, err := proto.Marshal(geoSiteList)
+	if err != nil {
+		t.Fatalf("proto.Marshal got unexpected error: %v", err)
+	}
+	return data
+}
+
+func TestParseRule(t *testing.T) {
+	for _, rule := range []string{
+		"domain:example.com",
+		"full:www.example.com:@ads,@cn",
+		"keyword:example",
+		`regexp:^(a|b):\d+\.example\.com$:@ads`,
+	} {
+		d, err := parseRule(rule)
+		if err != nil {
+			t.Errorf("parseRule(%q) got unexpected error: %v", rule, err)
+			continue
+		}
+		if got, err := formatRule(d); err != nil || got != rule {
+			t.Errorf("formatRule(parseRule(%q)) = %q, %v, want the same rule", rule, got, err)
+		}
+	}
+	for _, rule := range []string{"example.com", "include:cn", "domain:example.com:@"} {
+		if _, err := parseRule(rule); err == nil {
+			t.Errorf("parseRule(%q) = nil error, want invalid rule error", rule)
+		}
+	}
+}
+
+func TestDiffSeq(t *testing.T) {
+	testCases := []struct {
+		oldItems, newItems []string
+		removed, added     []int
+	}{
+		{nil, nil, nil, nil},
+		{[]string{"a", "b", "c"}, []string{"a", "b", "c"}, nil, nil},
+		{[]string{"a", "c", "e"}, []string{"b", "c", "d", "e", "f"}, []int{0}, []int{0, 2, 4}},
+		{[]string{"a", "b", "c"}, []string{"c", "a"}, []int{0, 1}, []int{1}},
+		{[]string{"a", "a", "b"}, []string{"a", "b", "b"}, []int{1}, []int{2}},
+	}
+	for _, tc := range testCases {
+		removed, added := diffSeq(tc.oldItems, tc.newItems)
+		if !slices.Equal(removed, tc.removed) || !slices.Equal(added, tc.added) {
+			t.Errorf("diffSeq(%v, %v) = %v, %v, want %v, %v", tc.oldItems, tc.newItems, removed, added, tc.removed, tc.added)
+		}
+		got, err := patchSeq(tc.oldItems, removed, added, func(i int) string { return tc.newItems[added[i]] })
+		if err != nil || !slices.Equal(got, tc.newItems) {
+			t.Errorf("patchSeq(%v) = %v, %v, want %v", tc.oldItems, got, err, tc.newItems)
+		}
+	}
+}
+
+func TestDiffApply(t *testing.T) {
+	oldSites := map[string][]string{
+		"APPLE":  {"domain:apple.com", "full:www.apple.cn:@cn"},
+		"CN":     {"domain:a.cn", "domain:b.cn", "domain:c.cn"},
+		"GOOGLE": {"domain:google.com", "full:ads.google.com:@ads"},
+		"OLD":    {"domain:old.example"},
+	}
+	newSites := map[string][]string{
+		"APPLE":  {"domain:apple.com", "full:www.apple.cn:@cn"},
+		"CN":     {"domain:a.cn", "domain:bb.cn", "domain:c.cn", "domain:d.cn"},
+		"GOOGLE": {"full:ads.google.com:@ads,@cn", "domain:google.com"},
+		"NEW":    {"keyword:new", `regexp:^new\d$`},
+	}
+	oldData := testDat(t, oldSites, "APPLE", "CN", "GOOGLE", "OLD")
+	newData := testDat(t, newSites, "CN", "APPLE", "GOOGLE", "NEW") // APPLE moved
+
+	delta, err := Diff(oldData, newData)
+	if err != nil {
+		t.Fatalf("Diff got unexpected error: %v", err)
+	}
+	if want := []string{"APPLE", "OLD"}; !slices.Equal(delta.RemovedSites, want) {
+		t.Errorf("Diff() removed sites = %v, want %v", delta.RemovedSites, want)
+	}
+	var names []string
+	for _, sd := range delta.Sites {
+		names = append(names, sd.Name)
+	}
+	if want := []string{"CN", "APPLE", "GOOGLE", "NEW"}; !slices.Equal(names, want) {
+		t.Errorf("Diff() sites = %v, want %v", names, want)
+	}
+	got, err := Apply(oldData, delta)
+	if err != nil {
+		t.Fatalf("Apply got unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, newData) {
+		t.Error("Apply() differs from the new file")
+	}
+
+	// Nothing changed
+	if delta, err := Diff(oldData, oldData); err != nil || len(delta.Sites) != 0 || len(delta.RemovedSites) != 0 {
+		t.Errorf("Diff() of the same file = %+v, %v, want empty delta", delta, err)
+	}
+
+	if _, err := Apply(newData, delta); err == nil {
+		t.Error("Apply() to the new file = nil error, want checksum error")
+	}
+	delta.Sites[0].Added[0].Rule = "domain:tampered.cn"
+	if _, err := Apply(oldData, delta); err == nil {
+		t.Error("Apply() of a tampered delta = nil error, want checksum error")
+	}
+
+	unsupported := new(router.GeoSiteList)
+	if err := proto.Unmarshal(newData, unsupported); err != nil {
+		t.Fatalf("proto.Unmarshal got unexpected error: %v", err)
+	}
+	unsupported.Entry[0].Domain[0].Attribute = []*router.Domain_Attribute{
+		{Key: "level", TypedValue: &router.Domain_Attribute_IntValue{IntValue: 2}},
+	}
+	unsupportedData, err := proto.Marshal(unsupported)
+	if err != nil {
+		t.Fatalf("proto.Marshal got unexpected error: %v", err)
+	}
+	if _, err := Diff(oldData, unsupportedData); err == nil {
+		t.Error("Diff() of an int attribute = nil error, want unsupported attribute error")
+	}
+}
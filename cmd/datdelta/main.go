@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+)
+
+const usage = `Usage:
+  datdelta diff --old old.dat --new new.dat --delta delta.json
+  datdelta apply --old old.dat --delta delta.json --new new.dat
+`
+
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	oldPath := fs.String("old", "", "Path of the old dat file")
+	newPath := fs.String("new", "dlc.dat", "Path of the new dat file")
+	deltaPath := fs.String("delta", "delta.json", "Path of the delta file to write")
+	fs.Parse(args)
+
+	oldData, err := os.ReadFile(*oldPath)
+	if err != nil {
+		return fmt.Errorf("failed to read old file: %w", err)
+	}
+	newData, err := os.ReadFile(*newPath)
+	if err != nil {
+		return fmt.Errorf("failed to read new file: %w", err)
+	}
+	delta, err := Diff(oldData, newData)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(delta); err != nil {
+		return err
+	}
+	if err := os.WriteFile(*deltaPath, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write delta file: %w", err)
+	}
+	fmt.Printf("delta %q of %d site(s) has been generated successfully (%d bytes)\n", *deltaPath, len(delta.Sites)+len(delta.RemovedSites), buf.Len())
+	return nil
+}
+
+func runApply(args []string) error {
+	fs := flag.NewFlagSet("apply", flag.ExitOnError)
+	oldPath := fs.String("old", "dlc.dat", "Path of the old dat file")
+	deltaPath := fs.String("delta", "delta.json", "Path of the delta file")
+	newPath := fs.String("new", "", "Path of the new dat file to write (empty to replace the old file)")
+	fs.Parse(args)
+
+	oldData, err := os.ReadFile(*oldPath)
+	if err != nil {
+		return fmt.Errorf("failed to read old file: %w", err)
+	}
+	deltaData, err := os.ReadFile(*deltaPath)
+	if err != nil {
+		return fmt.Errorf("failed to read delta file: %w", err)
+	}
+	delta := new(Delta)
+	if err := json.Unmarshal(deltaData, delta); err != nil {
+		return fmt.Errorf("failed to decode delta file: %w", err)
+	}
+	newData, err := Apply(oldData, delta)
+	if err != nil {
+		return err
+	}
+	if *newPath == "" {
+		*newPath = *oldPath
+	}
+	// Replace the file atomically, so that it is never left half written
+	tmpPath := *newPath + ".tmp"
+	if err := os.WriteFile(tmpPath, newData, 0644); err != nil {
+		return fmt.Errorf("failed to write new file: %w", err)
+	}
+	if err := os.Rename(tmpPath, *newPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write new file: %w", err)
+	}
+	fmt.Printf("dat %q has been reconstructed successfully (sha256 %s)\n", *newPath, delta.New.SHA256)
+	return nil
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Print(usage)
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "diff":
+		err = runDiff(os.Args[2:])
+	case "apply":
+		err = runApply(os.Args[2:])
+	default:
+		fmt.Print(usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Printf("[Fatal] critical error: %v\n", err)
+		os.Exit(1)
+	}
+}
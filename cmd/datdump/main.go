@@ -1,22 +1,29 @@
 package main
 
 import (
-	"bufio"
+	"bytes"
 	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
-	"github.com/v2fly/domain-list-community/internal/dlc"
+	"github.com/v2fly/domain-list-community/internal/artifact"
+	"github.com/v2fly/domain-list-community/internal/geositedb"
+	"github.com/v2fly/domain-list-community/internal/manifest"
+	"github.com/v2fly/domain-list-community/internal/plainyml"
 	router "github.com/v2fly/v2ray-core/v5/app/router/routercommon"
 	"google.golang.org/protobuf/proto"
 )
 
 var (
-	inputData   = flag.String("inputdata", "dlc.dat", "Name of the geosite dat file")
-	outputDir   = flag.String("outputdir", "./", "Directory to place all generated files")
-	exportLists = flag.String("exportlists", "", "Lists to be exported, separated by ',' (empty for _all_)")
+	inputData    = flag.String("inputdata", "dlc.dat", "Name of the geosite dat file, or the sing-box geosite.db file")
+	inputFormat  = flag.String("inputformat", "", "Format of the input file, 'dat' or 'geositedb' (empty to detect by the '.db' extension)")
+	outputDir    = flag.String("outputdir", "./", "Directory to place all generated files")
+	exportLists  = flag.String("exportlists", "", "Lists to be exported, separated by ',' (empty for _all_)")
+	checksums    = flag.String("checksums", "", "Checksum sidecars of every exported file, separated by ',' comma, e.g. 'sha256,sha512'")
+	manifestName = flag.String("manifest", "", "Name of the manifest file written by the generator in the output directory to add the exported files to, e.g. 'manifest.json' (empty to skip)")
 )
 
 type GeoSites struct {
@@ -29,11 +36,27 @@ func loadGeosite(path string) (*GeoSites, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to read geosite file: %w", err)
 	}
-	vgeositeList := new(router.GeoSiteList)
-	if err := proto.Unmarshal(data, vgeositeList); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal: %w", err)
+	gs := new(GeoSites)
+	format := *inputFormat
+	if format == "" && filepath.Ext(path) == ".db" {
+		format = "geositedb"
+	}
+	switch format {
+	case "", "dat":
+		vgeositeList := new(router.GeoSiteList)
+		if err := proto.Unmarshal(data, vgeositeList); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal: %w", err)
+		}
+		gs.Sites = vgeositeList.Entry
+	case "geositedb":
+		codes, err := geositedb.Read(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read geosite.db: %w", err)
+		}
+		gs.Sites = geositedb.ToSites(codes)
+	default:
+		return nil, fmt.Errorf("invalid input format: %q", format)
 	}
-	gs := &GeoSites{Sites: vgeositeList.Entry}
 	gs.SiteIdx = make(map[string]int, len(gs.Sites))
 	for i, site := range gs.Sites {
 		gs.SiteIdx[strings.ToUpper(site.CountryCode)] = i
@@ -41,33 +64,6 @@ func loadGeosite(path string) (*GeoSites, error) {
 	return gs, nil
 }
 
-func domain2Builder(d *router.Domain, b *strings.Builder) error {
-	switch d.Type {
-	case router.Domain_RootDomain:
-		b.WriteString(dlc.RuleTypeDomain)
-	case router.Domain_Full:
-		b.WriteString(dlc.RuleTypeFullDomain)
-	case router.Domain_Plain:
-		b.WriteString(dlc.RuleTypeKeyword)
-	case router.Domain_Regex:
-		b.WriteString(dlc.RuleTypeRegexp)
-	default:
-		return fmt.Errorf("invalid rule type: %+v", d.Type)
-	}
-	b.WriteByte(':')
-	b.WriteString(d.Value)
-	for i, attr := range d.Attribute {
-		if i == 0 {
-			b.WriteByte(':')
-		} else {
-			b.WriteByte(',')
-		}
-		b.WriteByte('@')
-		b.WriteString(attr.Key)
-	}
-	return nil
-}
-
 func exportSite(name string, gs *GeoSites) error {
 	idx, ok := gs.SiteIdx[strings.ToUpper(name)]
 	if !ok {
@@ -82,18 +78,7 @@ func exportSite(name string, gs *GeoSites) error {
 		return err
 	}
 	defer file.Close()
-	w := bufio.NewWriter(file)
-	fmt.Fprintf(w, "%q:\n", name)
-	var b strings.Builder
-	b.Grow(64)
-	for _, vdomain := range vDomains {
-		b.Reset()
-		if err := domain2Builder(vdomain, &b); err != nil {
-			return err
-		}
-		fmt.Fprintf(w, "  - %q\n", b.String())
-	}
-	return w.Flush()
+	return plainyml.WriteList(file, name, vDomains)
 }
 
 func exportAll(filename string, gs *GeoSites) error {
@@ -102,23 +87,7 @@ func exportAll(filename string, gs *GeoSites) error {
 		return err
 	}
 	defer file.Close()
-	w := bufio.NewWriter(file)
-	w.WriteString("lists:\n")
-	var b strings.Builder
-	b.Grow(64)
-	for _, site := range gs.Sites {
-		fmt.Fprintf(w, "  - name: %q\n", strings.ToLower(site.CountryCode))
-		fmt.Fprintf(w, "    length: %d\n", len(site.Domain))
-		w.WriteString("    rules:\n")
-		for _, vdomain := range site.Domain {
-			b.Reset()
-			if err := domain2Builder(vdomain, &b); err != nil {
-				return err
-			}
-			fmt.Fprintf(w, "      - %q\n", b.String())
-		}
-	}
-	return w.Flush()
+	return plainyml.WriteAll(file, gs.Sites)
 }
 
 func run() error {
@@ -143,23 +112,57 @@ func run() error {
 		exportListSlice = []string{"_all_"}
 	}
 
+	artifacts, err := artifact.ParseOptions("", *checksums, time.Time{})
+	if err != nil {
+		return fmt.Errorf("failed to parse checksums: %w", err)
+	}
+
+	// Add the exported files to the manifest of the generator
+	var m *manifest.Manifest
+	if *manifestName != "" {
+		if m, err = manifest.Read(filepath.Join(*outputDir, *manifestName)); err != nil {
+			return fmt.Errorf("failed to read manifest: %w", err)
+		}
+	}
+
 	failedCount := 0
 	for _, eplistname := range exportListSlice {
+		var filename string
+		var sites []*router.GeoSite
 		if strings.EqualFold(eplistname, "_all_") {
-			if err := exportAll(filepath.Base(*inputData)+"_plain.yml", geoSites); err != nil {
+			filename = filepath.Base(*inputData) + "_plain.yml"
+			if err := exportAll(filename, geoSites); err != nil {
 				fmt.Printf("[Error] failed to exportAll: %v\n", err)
 				failedCount++
 				continue
 			}
+			sites = geoSites.Sites
 		} else {
+			filename = eplistname + ".yml"
 			if err := exportSite(eplistname, geoSites); err != nil {
 				fmt.Printf("[Error] failed to exportSite: %v\n", err)
 				failedCount++
 				continue
 			}
+			sites = []*router.GeoSite{geoSites.Sites[geoSites.SiteIdx[strings.ToUpper(eplistname)]]}
+		}
+		if _, err := artifacts.Write(filepath.Join(*outputDir, filename)); err != nil {
+			fmt.Printf("[Error] failed to write checksums: %v\n", err)
+			failedCount++
+			continue
+		}
+		if err := m.AddFile(*outputDir, filename, "yml", len(sites), manifest.CountRules(sites), artifacts); err != nil {
+			fmt.Printf("[Error] failed to add %q to manifest: %v\n", filename, err)
+			failedCount++
+			continue
 		}
 		fmt.Printf("list: %q has been exported successfully\n", eplistname)
 	}
+	if m != nil {
+		if err := m.Write(filepath.Join(*outputDir, *manifestName)); err != nil {
+			return fmt.Errorf("failed to write manifest: %w", err)
+		}
+	}
 	if failedCount > 0 {
 		return fmt.Errorf("%d list(s) failed to be exported", failedCount)
 	}
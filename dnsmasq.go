@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Handlings of full type rules, which dnsmasq is unable to express because
+// its directives always match the subdomains of their domains
+const (
+	DnsmasqFullSuffix string = "suffix" // Approximated by the domain with its subdomains
+	DnsmasqFullDrop   string = "drop"
+)
+
+// dnsmasqDirectives are the dnsmasq directives written for every domain, in
+// the order of the lines.
+var dnsmasqDirectives = []string{"server", "ipset", "nftset"}
+
+// writeDnsmasq writes the lists as dnsmasq config lines like
+// `server=/example.com/114.114.114.114`, with the upstream of `server`, the
+// ipset sets of `ipset` and the nftables sets of `nftset` (like
+// `4#inet#fw4#setname`) in the options of each list. The sets of an option are
+// separated by spaces, like `gfwlist4 gfwlist6`. Keyword and regexp rules are
+// dropped.
+func writeDnsmasq(w io.Writer, lists []*ExportList, opts ExportOptions) error {
+	seen := make(map[string]bool)
+	for _, list := range lists {
+		var directives [][2]string
+		for _, directive := range dnsmasqDirectives {
+			value := opts.getFor(directive, list.Name, "")
+			if value == "" {
+				continue
+			}
+			if directive != "server" { // Sets separated by spaces, since options are separated by commas
+				sets := strings.Fields(value)
+				if slices.ContainsFunc(sets, func(set string) bool { return strings.Contains(set, ",") }) {
+					return fmt.Errorf("invalid %s %q of list %q", directive, value, list.Name)
+				}
+				value = strings.Join(sets, ",")
+			}
+			if strings.ContainsAny(value, "/ \t\r\n") {
+				return fmt.Errorf("invalid %s %q of list %q", directive, value, list.Name)
+			}
+			directives = append(directives, [2]string{directive, value})
+		}
+		if len(directives) == 0 {
+			return fmt.Errorf("none of %s is set for list %q", strings.Join(dnsmasqDirectives, ", "), list.Name)
+		}
+		full := opts.getFor("full", list.Name, DnsmasqFullSuffix)
+		if full != DnsmasqFullSuffix && full != DnsmasqFullDrop {
+			return fmt.Errorf("invalid handling of full type rules %q of list %q", full, list.Name)
+		}
+
+		for _, domain := range suffixDomains("dnsmasq", []*ExportList{list}, full == DnsmasqFullDrop) {
+			if seen[domain] { // The first list of a domain wins
+				continue
+			}
+			seen[domain] = true
+			for _, directive := range directives {
+				fmt.Fprintf(w, "%s=/%s/%s\n", directive[0], domain, directive[1])
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteDnsmasq(t *testing.T) {
+	lists := []*ExportList{
+		testExportList(t, "cn",
+			"domain:example.cn",
+			"full:www.example.com @cn",
+			"keyword:baidu", // Dropped
+		),
+		testExportList(t, "gfw",
+			"domain:example.cn", // Duplicated
+			"domain:example.org",
+			`regexp:^ads\d\.example\.org$`, // Dropped
+		),
+	}
+	testCases := []struct {
+		name string
+		opts ExportOptions
+		want string
+	}{
+		{
+			name: "server",
+			opts: ExportOptions{"server:cn": "114.114.114.114", "server": "127.0.0.1#5353"},
+			want: "server=/example.cn/114.114.114.114\n" +
+				"server=/www.example.com/114.114.114.114\n" +
+				"server=/example.org/127.0.0.1#5353\n",
+		},
+		{
+			name: "sets",
+			opts: ExportOptions{"ipset": "gfwlist", "nftset": "4#inet#fw4#gfwlist", "full": "drop"},
+			want: "ipset=/example.cn/gfwlist\n" +
+				"nftset=/example.cn/4#inet#fw4#gfwlist\n" +
+				"ipset=/example.org/gfwlist\n" +
+				"nftset=/example.org/4#inet#fw4#gfwlist\n",
+		},
+		{
+			name: "several sets",
+			opts: ExportOptions{"ipset:cn": " cn4  cn6 ", "nftset:cn": "4#inet#fw4#cn4 6#inet#fw4#cn6", "server:gfw": "1.1.1.1"},
+			want: "ipset=/example.cn/cn4,cn6\n" +
+				"nftset=/example.cn/4#inet#fw4#cn4,6#inet#fw4#cn6\n" +
+				"ipset=/www.example.com/cn4,cn6\n" +
+				"nftset=/www.example.com/4#inet#fw4#cn4,6#inet#fw4#cn6\n" +
+				"server=/example.org/1.1.1.1\n",
+		},
+	}
+	for _, tc := range testCases {
+		var buf bytes.Buffer
+		if err := writeDnsmasq(&buf, lists, tc.opts); err != nil {
+			t.Fatalf("writeDnsmasq(%s) got unexpected error: %v", tc.name, err)
+		}
+		if got := buf.String(); got != tc.want {
+			t.Errorf("writeDnsmasq(%s) = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+
+	for _, opts := range []ExportOptions{
+		nil,
+		{"server:cn": "114.114.114.114"},
+		{"server": "1.1.1.1/2"},
+		{"server": "1.1.1.1", "full": "exact"},
+		{"server": "1.1.1.1 8.8.8.8"},
+		{"ipset": "cn4,cn6"},
+	} {
+		if err := writeDnsmasq(new(bytes.Buffer), lists, opts); err == nil {
+			t.Errorf("writeDnsmasq(%v) = nil error, want error", opts)
+		}
+	}
+}
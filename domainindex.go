@@ -0,0 +1,117 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/v2fly/domain-list-community/internal/dlc"
+)
+
+// domainIndex holds domain and full type rules sorted by their reversed keys,
+// where the rule `domain:www.example.com` has the key "com.example.www.". It
+// is the depth-first order of a trie of reversed labels without its nodes:
+// every rule comes after the rules of its parent domains, whose keys are
+// prefixes of its key, and before the rules of its subdomains. So the index
+// is walked with the domain type rules of the parent domains on a stack, and
+// the rules of a domain are found by binary search.
+type domainIndex struct {
+	rules []*Entry
+}
+
+// newDomainIndex returns an index which is expected to hold size rules.
+func newDomainIndex(size int) domainIndex {
+	return domainIndex{rules: make([]*Entry, 0, size)}
+}
+
+// insert adds a domain or full type rule into the index, which is usable
+// after sort.
+func (x *domainIndex) insert(entry *Entry) {
+	x.rules = append(x.rules, entry)
+}
+
+// sort sorts the rules by their keys. A domain type rule comes before the full
+// type rule of the same domain.
+func (x *domainIndex) sort() {
+	slices.SortFunc(x.rules, func(a, b *Entry) int {
+		if c := strings.Compare(a.reversedKey(), b.reversedKey()); c != 0 {
+			return c
+		}
+		return cmp.Or(strings.Compare(a.Type, b.Type), strings.Compare(a.Plain, b.Plain))
+	})
+}
+
+// reversedKey returns the key of the domain of a domain or full type rule. It
+// is computed once per entry, which is shared by the lists including it.
+func (e *Entry) reversedKey() string {
+	if e.key == "" {
+		e.key = domainKey(e.Value)
+	}
+	return e.key
+}
+
+// domainKey returns the labels of the domain in reverse order, each followed
+// by a dot.
+func domainKey(domain string) string {
+	var sb strings.Builder
+	sb.Grow(len(domain) + 1)
+	for end := len(domain); ; {
+		start := strings.LastIndexByte(domain[:end], '.')
+		sb.WriteString(domain[start+1 : end])
+		sb.WriteByte('.')
+		if start < 0 {
+			return sb.String()
+		}
+		end = start
+	}
+}
+
+// appendNonRedundant appends the rules which are not covered by a domain type
+// rule of their parent domain, or of the same domain for a full type rule, in
+// the order of the index. A rule without attribute is covered by any such
+// rule, while a rule with attribute(s) can only be covered by rules with same
+// attr(s).
+func (x *domainIndex) appendNonRedundant(dst []*Entry) []*Entry {
+	var buf [16]*Entry
+	parents := buf[:0] // Domain type rules of the current domain and its parents
+	for _, rule := range x.rules {
+		key := rule.reversedKey()
+		for len(parents) > 0 && !strings.HasPrefix(key, parents[len(parents)-1].reversedKey()) {
+			parents = parents[:len(parents)-1]
+		}
+		if !isCovered(rule, parents) {
+			dst = append(dst, rule)
+		}
+		if rule.Type == dlc.RuleTypeDomain {
+			parents = append(parents, rule)
+		}
+	}
+	return dst
+}
+
+// isCovered reports whether one of the domain type rules of the domain of the
+// rule and its parents covers the rule.
+func isCovered(rule *Entry, parents []*Entry) bool {
+	for _, parent := range parents {
+		if parent.reversedKey() == rule.reversedKey() && rule.Type == dlc.RuleTypeDomain {
+			continue // A domain type rule does not trim itself
+		}
+		if len(rule.Attrs) == 0 || slices.Equal(parent.Attrs, rule.Attrs) {
+			return true
+		}
+	}
+	return false
+}
+
+// domainRules returns the rules of the domain, but not of its subdomains.
+func (x *domainIndex) domainRules(domain string) []*Entry {
+	key := domainKey(domain)
+	i, _ := slices.BinarySearchFunc(x.rules, key, func(rule *Entry, key string) int {
+		return strings.Compare(rule.reversedKey(), key)
+	})
+	j := i
+	for j < len(x.rules) && x.rules[j].reversedKey() == key {
+		j++
+	}
+	return x.rules[i:j]
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/v2fly/domain-list-community/internal/dlc"
+)
+
+// polishListLegacy is the map based implementation of polishList replaced by
+// domainIndex, kept as the reference of the differential tests.
+func polishListLegacy(roughMap map[string]*Entry) []*Entry {
+	finalList := make([]*Entry, 0, len(roughMap))
+	queuingList := make([]*Entry, 0, len(roughMap))
+	parentsMap := make(map[string]bool)
+	for _, entry := range roughMap {
+		switch entry.Type { // Bypass regexp and keyword
+		case dlc.RuleTypeRegexp, dlc.RuleTypeKeyword:
+			finalList = append(finalList, entry)
+		case dlc.RuleTypeDomain:
+			parentsMap[entry.Value] = true
+			if len(entry.Attrs) != 0 {
+				// `sub.example.org:@attr1,@attr2`
+				// Ensure no dot exists except the domain (entry.Value) part
+				_, domainAndAttrs, _ := strings.Cut(entry.Plain, ":")
+				parentsMap[domainAndAttrs] = true
+			}
+			queuingList = append(queuingList, entry)
+		case dlc.RuleTypeFullDomain:
+			queuingList = append(queuingList, entry)
+		}
+	}
+
+	for _, qentry := range queuingList {
+		isRedundant := false
+		var pd string // To be parent domain (with attrs)
+		if len(qentry.Attrs) == 0 {
+			pd = qentry.Value
+		} else {
+			_, pd, _ = strings.Cut(qentry.Plain, ":")
+		}
+		if qentry.Type == dlc.RuleTypeFullDomain {
+			pd = "." + pd // So that `domain:example.org` overrides `full:example.org`
+		}
+		for {
+			var hasParent bool
+			_, pd, hasParent = strings.Cut(pd, ".") // Go for next parent
+			if !hasParent {
+				break
+			}
+			if parentsMap[pd] {
+				isRedundant = true
+				break
+			}
+		}
+		if !isRedundant {
+			finalList = append(finalList, qentry)
+		}
+	}
+	// Sort final entries
+	slices.SortFunc(finalList, func(a, b *Entry) int {
+		return strings.Compare(a.Plain, b.Plain)
+	})
+	return finalList
+}
+
+func TestPolishListEdgeCases(t *testing.T) {
+	rules := []struct{ typ, rule string }{
+		{"domain", "com @cn"},
+		{"full", "example.com @cn"},          // Redundant, same attribute on tld
+		{"full", "example.com @ads"},         // Kept, different attribute
+		{"domain", "sub.example.com"},        // Redundant, no attribute
+		{"domain", "example.org @ads @cn"},   // Kept, no parent domain rule
+		{"full", "example.org @ads @cn"},     // Redundant, same attributes
+		{"full", "example.org @cn"},          // Kept, different attributes
+		{"domain", "example.net"},            // Kept, no self trimming
+		{"domain", "example.net @ads"},       // Kept, no self trimming
+		{"full", "www.example.net @ads"},     // Redundant, same attribute
+		{"full", "example.net @cn"},          // Kept, different attribute
+		{"full", "example.net"},              // Redundant, no attribute
+		{"domain", "a.b.c.example.edu @ads"}, // Kept, deep subdomain
+		{"full", "www.example.edu"},          // Kept, no parent domain rule
+	}
+	roughMap := make(map[string]*Entry, len(rules))
+	for _, r := range rules {
+		entry, _, err := parseEntry(r.typ, r.rule)
+		if err != nil {
+			t.Fatalf("parseEntry(%q, %q) got unexpected error: %v", r.typ, r.rule, err)
+		}
+		roughMap[entry.Plain] = entry
+	}
+	want := []string{
+		"domain:a.b.c.example.edu:@ads",
+		"domain:com:@cn",
+		"domain:example.net",
+		"domain:example.net:@ads",
+		"domain:example.org:@ads,@cn",
+		"full:example.com:@ads",
+		"full:example.net:@cn",
+		"full:example.org:@cn",
+		"full:www.example.edu",
+	}
+	assertPlains(t, "polishList", polishList(roughMap), want)
+	assertPlains(t, "polishListLegacy", polishListLegacy(roughMap), want)
+}
+
+func TestDomainKey(t *testing.T) {
+	testCases := []struct {
+		domain string
+		want   string
+	}{
+		{"com", "com."},
+		{"example.com", "com.example."},
+		{"www.example.com", "com.example.www."},
+	}
+	for _, tc := range testCases {
+		if got := domainKey(tc.domain); got != tc.want {
+			t.Errorf("domainKey(%q) = %q, want %q", tc.domain, got, tc.want)
+		}
+	}
+}
+
+func TestDomainIndex(t *testing.T) {
+	index := newDomainIndex(0)
+	for _, plain := range []string{"full:example.com", "domain:example-cdn.com", "domain:www.example.com", "domain:com", "domain:example.com", "domain:example.com:@ads"} {
+		typ, rule, _ := strings.Cut(plain, ":")
+		entry, _, err := parseEntry(typ, strings.Replace(rule, ":", " ", 1))
+		if err != nil {
+			t.Fatalf("parseEntry(%q) got unexpected error: %v", plain, err)
+		}
+		index.insert(entry)
+	}
+	index.sort()
+	want := []string{"domain:com", "domain:example-cdn.com", "domain:example.com", "domain:example.com:@ads", "full:example.com", "domain:www.example.com"}
+	assertPlains(t, "domainIndex", index.rules, want)
+	if got := index.domainRules("example.com"); len(got) != 3 || got[2].Plain != "full:example.com" {
+		t.Errorf("domainRules(\"example.com\") = %v, want the 3 rules of example.com", got)
+	}
+	if got := index.domainRules("example.org"); len(got) != 0 {
+		t.Errorf("domainRules(\"example.org\") = %v, want none", got)
+	}
+}
+
+// TestPolishListDifferential makes sure that polishList gives identical
+// results to the legacy implementation on every list of the full data set.
+func TestPolishListDifferential(t *testing.T) {
+	processor := loadTestData(t)
+	for name, pl := range processor.parsedListByName {
+		got := polishList(pl.RoughEntries)
+		want := polishListLegacy(pl.RoughEntries)
+		if !slices.Equal(got, want) {
+			t.Errorf("polishList(%q) differs from legacy: got %d entries, want %d", name, len(got), len(want))
+		}
+	}
+}
+
+func BenchmarkPolishList(b *testing.B) {
+	processor := loadTestData(b)
+	roughMaps := make([]map[string]*Entry, 0, len(processor.parsedListByName))
+	for _, pl := range processor.parsedListByName {
+		roughMaps = append(roughMaps, pl.RoughEntries)
+	}
+	benchmarks := []struct {
+		name   string
+		polish func(map[string]*Entry) []*Entry
+	}{
+		{"legacy", polishListLegacy},
+		{"index", polishList},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				for _, roughMap := range roughMaps {
+					bm.polish(roughMap)
+				}
+			}
+		})
+	}
+}
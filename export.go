@@ -0,0 +1,491 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/v2fly/domain-list-community/internal/dlc"
+)
+
+var (
+	exportFormats = flag.String("exportformats", "txt", "Formats of exported lists, separated by ',' comma, e.g. 'txt,singbox,srs'")
+	exportOptions = flag.String("exportoptions", "", "Options of exported lists, separated by ',' comma, e.g. 'version=1'; 'key:list=value' overrides the option for a list")
+)
+
+const (
+	maxDroppedShown   = 5                                                // Number of dropped rules shown in a warning
+	generatorHomepage = "https://github.com/v2fly/domain-list-community" // Generator named in the headers and metadata of outputs
+)
+
+// ExportList is a resolved list, or the part of it selected by attributes,
+// to be exported.
+type ExportList struct {
+	Name    string   // Lower case name with the attribute filter, e.g. "google@ads"
+	Entries []*Entry // Sorted entries without redundant subdomains
+	Known   []string // Known domains from other lists, for formats expanding domain type rules
+}
+
+// ListFormat is a format in which lists are exported.
+type ListFormat struct {
+	Ext     string   // Extension of the exported file
+	Options []string // Keys of the options supported by the format
+	Expand  bool     // Whether the lists need the known domains of the lists named by the "expand" option
+	Write   func(w io.Writer, lists []*ExportList, opts ExportOptions) error
+}
+
+var listFormats = map[string]*ListFormat{
+	"txt":     {Ext: ".txt", Write: writePlainList},
+	"json":    {Ext: ".json", Options: []string{"provenance", "metadata"}, Write: writeJSON},
+	"ndjson":  {Ext: ".ndjson", Options: []string{"provenance"}, Write: writeNDJSON},
+	"singbox": {Ext: ".singbox.json", Options: []string{"version"}, Write: writeSingboxSource},
+	"srs":     {Ext: ".srs", Options: []string{"version"}, Write: writeSingboxBinary},
+
+	"mihomo":      {Ext: ".mihomo.yaml", Options: []string{"behavior"}, Write: writeMihomoYAML},
+	"mihomo-text": {Ext: ".mihomo.list", Options: []string{"behavior"}, Write: writeMihomoText},
+	"mrs":         {Ext: ".mrs", Write: writeMihomoBinary},
+
+	"surge":        {Ext: ".surge.list", Write: DialectSurge.write()},
+	"loon":         {Ext: ".loon.list", Write: DialectLoon.write()},
+	"shadowrocket": {Ext: ".shadowrocket.list", Write: DialectShadowrocket.write()},
+	"stash":        {Ext: ".stash.list", Write: DialectStash.write()},
+	"domainset":    {Ext: ".domainset.txt", Write: writeDomainSet},
+	"quanx":        {Ext: ".quanx.list", Options: []string{"policy"}, Write: writeQuanX},
+
+	"adguard": {Ext: ".adguard.txt", Options: []string{"title", "version", "expires"}, Write: DialectAdGuard.write()},
+	"adblock": {Ext: ".adblock.txt", Options: []string{"title", "version", "expires"}, Write: DialectAdblock.write()},
+
+	"dnsmasq": {Ext: ".dnsmasq.conf", Options: []string{"server", "ipset", "nftset", "full"}, Write: writeDnsmasq},
+	"unbound": {Ext: ".unbound.conf", Options: []string{"zone", "forward"}, Write: writeUnbound},
+	"rpz":     {Ext: ".rpz.zone", Options: []string{"action"}, Write: writeRPZ},
+	"nftset":  {Ext: ".nft", Options: []string{"name", "timeout", "interval"}, Write: writeNftSet},
+	"hosts":   {Ext: ".hosts", Options: []string{"address", "expand"}, Expand: true, Write: writeHosts},
+	"pac":     {Ext: ".pac", Options: []string{"proxy", "default"}, Write: writePAC},
+
+	"squid":       {Ext: ".squid.txt", Write: writeSquid},
+	"squid-regex": {Ext: ".squid-regex.txt", Write: writeSquidRegex},
+	"nginx":       {Ext: ".nginx.map", Options: []string{"target"}, Write: writeNginxMap},
+	"haproxy":     {Ext: ".haproxy.map", Options: []string{"target"}, Write: writeHAProxyMap},
+
+	"smartdns-set":       {Ext: ".smartdns.list", Write: writeSmartDNSSet},
+	"smartdns":           {Ext: ".smartdns.conf", Options: []string{"group"}, Write: writeSmartDNS},
+	"adguardhome":        {Ext: ".adguardhome.txt", Options: []string{"upstream", "linelength"}, Write: writeAdGuardHome},
+	"blocky":             {Ext: ".blocky.txt", Write: writeBlocky},
+	"blocky-conditional": {Ext: ".blocky.yaml", Options: []string{"upstream"}, Write: writeBlockyConditional},
+}
+
+// ExportOptions are the options of exporters by key. An option with the key
+// suffixed with ':' and a list name, like `policy:cn`, applies to that list
+// only and overrides the option for all lists.
+type ExportOptions map[string]string
+
+// get returns the option of the key, or def if it is not set.
+func (o ExportOptions) get(key, def string) string {
+	if value, ok := o[key]; ok {
+		return value
+	}
+	return def
+}
+
+// getFor returns the option of the key for the named list, or def if it is
+// not set.
+func (o ExportOptions) getFor(key, list, def string) string {
+	if value, ok := o[key+":"+list]; ok {
+		return value
+	}
+	return o.get(key, def)
+}
+
+// parseExportFormats parses format names like `txt,srs`.
+func parseExportFormats(raw string) ([]string, error) {
+	var names []string
+	for name := range strings.SplitSeq(raw, ",") {
+		if name = strings.ToLower(strings.TrimSpace(name)); name == "" || slices.Contains(names, name) {
+			continue
+		}
+		if _, ok := listFormats[name]; !ok {
+			return nil, fmt.Errorf("unknown export format: %q", name)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// parseExportOptions parses options like `version=1,policy:cn=DIRECT`, whose
+// keys must be supported by at least one of the formats.
+func parseExportOptions(raw string, formats []string) (ExportOptions, error) {
+	opts := make(ExportOptions)
+	for rawOpt := range strings.SplitSeq(raw, ",") {
+		if rawOpt = strings.TrimSpace(rawOpt); rawOpt == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(rawOpt, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid export option: %q", rawOpt)
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		optName, _, _ := strings.Cut(key, ":")
+		if !slices.ContainsFunc(formats, func(format string) bool {
+			return slices.Contains(listFormats[format].Options, optName)
+		}) {
+			return nil, fmt.Errorf("unknown export option %q for formats %q", optName, strings.Join(formats, ","))
+		}
+		opts[key] = strings.TrimSpace(value)
+	}
+	return opts, nil
+}
+
+// parseAttrFilter parses the attribute filter after the first '@' of a name
+// like `cn@ads@-cn`, into the attributes which the rules must have and the
+// ones after '-' which they must not have, both sorted and deduplicated. '!'
+// is a character of attributes like `@!cn`, as in v2ray routing.
+func parseAttrFilter(rawAttrs string) (*Inclusion, error) {
+	filter := new(Inclusion)
+	for attr := range strings.SplitSeq(strings.ToLower(rawAttrs), "@") {
+		battr, isBan := strings.CutPrefix(attr, "-")
+		if !validateAttrChars(battr) {
+			return nil, fmt.Errorf("invalid attribute: %q", attr)
+		}
+		if isBan {
+			filter.BanAttrs = append(filter.BanAttrs, battr)
+		} else {
+			filter.MustAttrs = append(filter.MustAttrs, battr)
+		}
+	}
+	slices.Sort(filter.MustAttrs)
+	filter.MustAttrs = slices.Compact(filter.MustAttrs)
+	slices.Sort(filter.BanAttrs)
+	filter.BanAttrs = slices.Compact(filter.BanAttrs)
+	for _, attr := range filter.MustAttrs {
+		if slices.Contains(filter.BanAttrs, attr) {
+			return nil, fmt.Errorf("attribute %q is both required and banned", attr)
+		}
+	}
+	return filter, nil
+}
+
+// attrFilterName returns the canonical name of the list selected by the
+// attribute filter, with the required attributes before the banned ones, so
+// that the same selection is always exported to the same file.
+func attrFilterName(listName string, filter *Inclusion) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(listName))
+	for _, attr := range filter.MustAttrs {
+		b.WriteString("@" + attr)
+	}
+	for _, attr := range filter.BanAttrs {
+		b.WriteString("@-" + attr)
+	}
+	return b.String()
+}
+
+// selectList returns the named list to export, or nil if the list does not
+// exist. A name like `google@ads` selects the rules having all the attributes
+// after '@', and `cn@-ads` the rules without the attribute after '-', like
+// selective inclusions. The selected rules are polished again so that no rule
+// is lost for a parent rule filtered out, and the name of the list is
+// canonicalized by attrFilterName.
+func (p *Processor) selectList(name string) (*ExportList, error) {
+	listName, rawAttrs, hasAttrs := strings.Cut(name, "@")
+	var filter *Inclusion
+	if hasAttrs {
+		var err error
+		if filter, err = parseAttrFilter(rawAttrs); err != nil {
+			return nil, err
+		}
+	}
+	pl, exist := p.parsedListByName[strings.ToUpper(listName)]
+	if !exist {
+		return nil, nil
+	}
+	if !hasAttrs {
+		return &ExportList{Name: strings.ToLower(listName), Entries: pl.FinalEntries}, nil
+	}
+	roughEntries := make(map[string]*Entry)
+	for _, entry := range pl.RoughEntries {
+		if isMatchAttrFilters(entry, filter) {
+			roughEntries[entry.Plain] = entry
+		}
+	}
+	return &ExportList{Name: attrFilterName(listName, filter), Entries: polishList(roughEntries)}, nil
+}
+
+// selectTaskLists returns the lists to export by the task. Lists of allowlist
+// tasks may be selected by attributes like `google@ads`, and the attribute
+// filter of the task, like `@ads`, applies to every list of the task.
+func (p *Processor) selectTaskLists(task DatTask) ([]*ExportList, error) {
+	var lists []*ExportList
+	if task.Mode == ModeAllowlist {
+		for _, name := range task.Lists {
+			el, err := p.selectList(strings.TrimSpace(name) + task.Attrs)
+			if err != nil {
+				return nil, fmt.Errorf("failed to select list %q: %w", name, err)
+			}
+			if el == nil {
+				return nil, fmt.Errorf("list %q not found for allowlist task", name)
+			}
+			if len(el.Entries) == 0 {
+				warner.warnf(WarnExportMissing, "list %q is empty in task %q", name, task.Name)
+				continue
+			}
+			if !slices.ContainsFunc(lists, func(list *ExportList) bool { return list.Name == el.Name }) {
+				lists = append(lists, el)
+			}
+		}
+		if len(lists) == 0 {
+			return nil, fmt.Errorf("allowlist needs at least one valid list")
+		}
+		return lists, nil
+	}
+
+	deniedMap := make(map[string]bool)
+	if task.Mode == ModeDenylist {
+		for _, name := range task.Lists {
+			plname := strings.ToUpper(strings.TrimSpace(name))
+			if _, exist := p.parsedListByName[plname]; exist {
+				deniedMap[plname] = true
+			} else {
+				warner.warnf(WarnDenylistMissing, "list %q not found in denylist task %q", name, task.Name)
+			}
+		}
+		if len(deniedMap) == 0 {
+			warner.warnf(WarnDenylistEmpty, "nothing to deny in task %q", task.Name)
+		}
+	}
+	for _, plname := range slices.Sorted(maps.Keys(p.parsedListByName)) {
+		if deniedMap[plname] {
+			continue
+		}
+		el, err := p.selectList(strings.ToLower(plname) + task.Attrs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to select list %q: %w", plname, err)
+		}
+		if len(el.Entries) != 0 {
+			lists = append(lists, el)
+		}
+	}
+	return lists, nil
+}
+
+// exportTask writes the lists selected by the task in the format of the task.
+func (p *Processor) exportTask(task DatTask) error {
+	lists, err := p.selectTaskLists(task)
+	if err != nil {
+		return err
+	}
+	opts := make(ExportOptions, len(task.Options))
+	for key, value := range task.Options {
+		opts[strings.ToLower(key)] = value
+	}
+	filename := strings.ToLower(filepath.Base(task.Name))
+	if err := writeOutput(filename, func(w io.Writer) error {
+		return p.writeLists(w, listFormats[task.Format], lists, opts)
+	}); err != nil {
+		return fmt.Errorf("failed to write file %q: %w", filename, err)
+	}
+	if err := addOutput(filename, task.Format, len(lists), countListRules(lists)); err != nil {
+		return err
+	}
+	fmt.Printf("%s %q has been generated successfully\n", task.Format, filename)
+	return nil
+}
+
+// writeLists writes the lists in the format, with the known domains of the
+// lists if the format needs them.
+func (p *Processor) writeLists(w io.Writer, lf *ListFormat, lists []*ExportList, opts ExportOptions) error {
+	if lf.Expand {
+		var err error
+		if lists, err = p.withKnown(lists, opts); err != nil {
+			return err
+		}
+	}
+	return lf.Write(w, lists, opts)
+}
+
+// withKnown returns copies of the lists with the domains of the domain and
+// full type rules of the lists named by their `expand` option, separated by
+// spaces, or of all lists if it is `all`.
+func (p *Processor) withKnown(lists []*ExportList, opts ExportOptions) ([]*ExportList, error) {
+	knownByNames := make(map[string][]string)
+	expanded := make([]*ExportList, len(lists))
+	for i, list := range lists {
+		names := strings.Fields(strings.ToLower(opts.getFor("expand", list.Name, "")))
+		if len(names) == 1 && names[0] == "all" {
+			names = nil
+			for plname := range p.parsedListByName {
+				names = append(names, strings.ToLower(plname))
+			}
+		}
+		slices.Sort(names)
+		names = slices.Compact(names)
+		key := strings.Join(names, " ")
+		known, exist := knownByNames[key]
+		if !exist {
+			for _, name := range names {
+				pl, exist := p.parsedListByName[strings.ToUpper(name)]
+				if !exist {
+					return nil, fmt.Errorf("list %q to expand list %q not found", name, list.Name)
+				}
+				for _, entry := range pl.FinalEntries {
+					if entry.Type == dlc.RuleTypeDomain || entry.Type == dlc.RuleTypeFullDomain {
+						known = append(known, entry.Value)
+					}
+				}
+			}
+			slices.Sort(known)
+			known = slices.Compact(known)
+			knownByNames[key] = known
+		}
+		expanded[i] = &ExportList{Name: list.Name, Entries: list.Entries, Known: known}
+	}
+	return expanded, nil
+}
+
+// mergeEntries returns the sorted entries of all the lists without redundant
+// subdomains.
+func mergeEntries(lists []*ExportList) []*Entry {
+	if len(lists) == 1 {
+		return lists[0].Entries
+	}
+	roughEntries := make(map[string]*Entry)
+	for _, list := range lists {
+		for _, entry := range list.Entries {
+			roughEntries[entry.Plain] = entry
+		}
+	}
+	return polishList(roughEntries)
+}
+
+// stripAttrs returns the entries without attributes, polished again, for the
+// formats which are unable to express attributes. Otherwise a rule with
+// attributes would be duplicated by the same rule without attribute, and would
+// not trim its subdomains with other attributes.
+func stripAttrs(entries []*Entry) []*Entry {
+	roughEntries := make(map[string]*Entry, len(entries))
+	for _, entry := range entries {
+		if len(entry.Attrs) != 0 {
+			entry = &Entry{Type: entry.Type, Value: entry.Value, Plain: entry.Type + ":" + entry.Value, File: entry.File, Line: entry.Line, key: entry.key}
+		}
+		roughEntries[entry.Plain] = entry
+	}
+	return polishList(roughEntries)
+}
+
+// buildTime returns the time of the build in UTC, which is SOURCE_DATE_EPOCH
+// if it is set for reproducible builds.
+func buildTime() (time.Time, error) {
+	raw := os.Getenv("SOURCE_DATE_EPOCH")
+	if raw == "" {
+		return time.Now().UTC(), nil
+	}
+	sec, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH: %w", err)
+	}
+	return time.Unix(sec, 0).UTC(), nil
+}
+
+// warnDropped reports the rules of the lists dropped by the format, so that
+// lossy exports are never silent.
+func warnDropped(format string, lists []*ExportList, dropped []*Entry) {
+	warnLossy(lists, dropped, "dropped in format "+format)
+}
+
+// warnApproximated reports the rules of the lists which the format expresses
+// by rules matching more domains.
+func warnApproximated(format string, lists []*ExportList, approximated []*Entry) {
+	warnLossy(lists, approximated, "approximated by broader rules in format "+format)
+}
+
+func warnLossy(lists []*ExportList, entries []*Entry, what string) {
+	if len(entries) == 0 {
+		return
+	}
+	names := make([]string, len(lists))
+	for i, list := range lists {
+		names[i] = list.Name
+	}
+	shown := entries[:min(len(entries), maxDroppedShown)]
+	plains := make([]string, 0, len(shown)+1)
+	for _, entry := range shown {
+		plains = append(plains, entry.Plain)
+	}
+	if len(entries) > maxDroppedShown {
+		plains = append(plains, "...")
+	}
+	warner.warnf(WarnExportLossy, "%d rule(s) of list %q %s: %s",
+		len(entries), strings.Join(names, ","), what, strings.Join(plains, " "))
+}
+
+// suffixDomains returns the domains of the domain type rules of the lists,
+// and of the full type rules unless dropFull, for formats which always match
+// the subdomains of their domains. The full type rules approximated by them
+// and the other rules dropped are reported.
+func suffixDomains(format string, lists []*ExportList, dropFull bool) []string {
+	entries := stripAttrs(mergeEntries(lists))
+	domains := make([]string, 0, len(entries))
+	var dropped, approximated []*Entry
+	for _, entry := range entries {
+		switch {
+		case entry.Type == dlc.RuleTypeDomain:
+		case entry.Type == dlc.RuleTypeFullDomain && !dropFull:
+			approximated = append(approximated, entry)
+		default:
+			dropped = append(dropped, entry)
+			continue
+		}
+		domains = append(domains, entry.Value)
+	}
+	warnDropped(format, lists, dropped)
+	warnApproximated(format, lists, approximated)
+	return domains
+}
+
+func writePlainList(w io.Writer, lists []*ExportList, _ ExportOptions) error {
+	for _, entry := range mergeEntries(lists) {
+		fmt.Fprintln(w, entry.Plain)
+	}
+	return nil
+}
+
+// writeOutput creates the named file in the output directory and fills it by
+// the write function through a buffered writer, whose errors are reported
+// when it is flushed. The file is removed if it fails to be written. Its
+// compressed variants and checksum sidecars are written along with it.
+func writeOutput(filename string, write func(w io.Writer) error) (err error) {
+	path := filepath.Join(*outputDir, filename)
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			file.Close()
+			os.Remove(path)
+		}
+	}()
+	w := bufio.NewWriter(file)
+	if err := write(w); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if artifacts != nil {
+		if _, err := artifacts.Write(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
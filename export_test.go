@@ -0,0 +1,198 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSelectList(t *testing.T) {
+	dataPath := t.TempDir()
+	content := "domain:example.com @ads\nfull:ads.example.com @ads @cn\ndomain:example.org\nkeyword:tracker @ads\ndomain:example.net @!cn\n"
+	if err := os.WriteFile(filepath.Join(dataPath, "source"), []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write test data: %v", err)
+	}
+	processor := &Processor{parsedListByName: make(map[string]*ParsedList)}
+	if err := processor.loadDataDir(dataPath); err != nil {
+		t.Fatalf("loadDataDir got unexpected error: %v", err)
+	}
+	if err := processor.resolveAll(); err != nil {
+		t.Fatalf("resolveAll got unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		wantName string
+		want     []string
+	}{
+		{"source", "source", []string{"domain:example.com:@ads", "domain:example.net:@!cn", "domain:example.org", "full:ads.example.com:@ads,@cn", "keyword:tracker:@ads"}},
+		{"Source@ADS", "source@ads", []string{"domain:example.com:@ads", "full:ads.example.com:@ads,@cn", "keyword:tracker:@ads"}},
+		{"source@cn", "source@cn", []string{"full:ads.example.com:@ads,@cn"}},
+		{"source@ads@cn", "source@ads@cn", []string{"full:ads.example.com:@ads,@cn"}},
+		{"source@none", "source@none", []string{}},
+		{"source@!cn", "source@!cn", []string{"domain:example.net:@!cn"}},
+		{"source@ads@!cn", "source@!cn@ads", []string{}},
+		{"source@-!cn", "source@-!cn", []string{"domain:example.com:@ads", "domain:example.org", "full:ads.example.com:@ads,@cn", "keyword:tracker:@ads"}},
+		{"source@-ads", "source@-ads", []string{"domain:example.net:@!cn", "domain:example.org"}},
+		{"source@ads@-cn", "source@ads@-cn", []string{"domain:example.com:@ads", "keyword:tracker:@ads"}},
+		{"source@cn@ads", "source@ads@cn", []string{"full:ads.example.com:@ads,@cn"}},
+		{"source@-cn@ADS@ads", "source@ads@-cn", []string{"domain:example.com:@ads", "keyword:tracker:@ads"}},
+		{"source@-none@-cn", "source@-cn@-none", []string{"domain:example.com:@ads", "domain:example.net:@!cn", "domain:example.org", "keyword:tracker:@ads"}},
+	}
+	for _, tc := range testCases {
+		el, err := processor.selectList(tc.name)
+		if err != nil {
+			t.Errorf("selectList(%q) got unexpected error: %v", tc.name, err)
+			continue
+		}
+		if el.Name != tc.wantName {
+			t.Errorf("selectList(%q).Name = %q, want %q", tc.name, el.Name, tc.wantName)
+		}
+		assertPlains(t, "selectList("+tc.name+")", el.Entries, tc.want)
+	}
+
+	if el, err := processor.selectList("not-exist"); el != nil || err != nil {
+		t.Errorf("selectList(\"not-exist\") = %v, %v, want nil, nil", el, err)
+	}
+	for _, name := range []string{"source@", "source@-", "source@a b", "source@ads@-ads", "source@!cn@-!cn", "not-exist@-"} {
+		if _, err := processor.selectList(name); err == nil {
+			t.Errorf("selectList(%q) = nil error, want invalid attribute filter error", name)
+		}
+	}
+}
+
+func TestStripAttrs(t *testing.T) {
+	el := testExportList(t, "test",
+		"domain:example.com @ads",
+		"domain:example.com",
+		"full:www.example.com @cn",
+		"full:example.org @cn",
+		"keyword:tracker @ads",
+	)
+	want := []string{"domain:example.com", "full:example.org", "keyword:tracker"}
+	assertPlains(t, "stripAttrs", stripAttrs(el.Entries), want)
+}
+
+func TestParseExportOptions(t *testing.T) {
+	opts, err := parseExportOptions(" version=1 , Version:CN = 3 ", []string{"txt", "srs"})
+	if err != nil {
+		t.Fatalf("parseExportOptions got unexpected error: %v", err)
+	}
+	if got := opts.getFor("version", "cn", "2"); got != "3" {
+		t.Errorf("getFor(\"version\", \"cn\") = %q, want %q", got, "3")
+	}
+	if got := opts.getFor("version", "google", "2"); got != "1" {
+		t.Errorf("getFor(\"version\", \"google\") = %q, want %q", got, "1")
+	}
+	if got := opts.get("unset", "default"); got != "default" {
+		t.Errorf("get(\"unset\") = %q, want %q", got, "default")
+	}
+
+	for _, raw := range []string{"version", "unknown=1"} {
+		if _, err := parseExportOptions(raw, []string{"srs"}); err == nil {
+			t.Errorf("parseExportOptions(%q) = nil error, want error", raw)
+		}
+	}
+	if _, err := parseExportOptions("version=1", []string{"txt"}); err == nil {
+		t.Error("parseExportOptions(\"version=1\") for txt = nil error, want unknown option error")
+	}
+}
+
+// testExportList returns a list of the rules like `domain:example.com @ads`,
+// polished as a resolved list.
+func testExportList(t *testing.T, name string, rules ...string) *ExportList {
+	t.Helper()
+	roughEntries := make(map[string]*Entry, len(rules))
+	for _, rule := range rules {
+		typ, value, _ := strings.Cut(rule, ":")
+		entry, _, err := parseEntry(typ, value)
+		if err != nil {
+			t.Fatalf("parseEntry(%q) got unexpected error: %v", rule, err)
+		}
+		roughEntries[entry.Plain] = entry
+	}
+	return &ExportList{Name: name, Entries: polishList(roughEntries)}
+}
+
+func TestExportTask(t *testing.T) {
+	dataPath := t.TempDir()
+	files := map[string]string{
+		"first":  "domain:example.com\nfull:ads.example.org @ads\n",
+		"second": "domain:example.net @ads\n",
+		"third":  "full:www.example.com\ndomain:example.io @!cn\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dataPath, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write test data %q: %v", name, err)
+		}
+	}
+	processor := &Processor{parsedListByName: make(map[string]*ParsedList)}
+	if err := processor.loadDataDir(dataPath); err != nil {
+		t.Fatalf("loadDataDir got unexpected error: %v", err)
+	}
+	if err := processor.resolveAll(); err != nil {
+		t.Fatalf("resolveAll got unexpected error: %v", err)
+	}
+
+	defer func(dir string) { *outputDir = dir }(*outputDir)
+	*outputDir = t.TempDir()
+	profile := filepath.Join(*outputDir, "profile.json")
+	tasks := `[
+		{"name": "ads.list", "mode": "allowlist", "lists": ["first@ads", "second"], "format": "surge"},
+		{"name": "Rest.txt", "mode": "denylist", "lists": ["second"], "format": "domainset"},
+		{"name": "all.srs", "mode": "all", "format": "srs", "options": {"Version": "1"}},
+		{"name": "all-ads.txt", "mode": "all", "format": "domainset", "attrs": "@ads"},
+		{"name": "not-cn.txt", "mode": "allowlist", "lists": ["third"], "format": "domainset", "attrs": "@!cn"},
+		{"name": "first.hosts", "mode": "allowlist", "lists": ["first"], "format": "hosts", "options": {"expand": "all"}}
+	]`
+	if err := os.WriteFile(profile, []byte(tasks), 0644); err != nil {
+		t.Fatalf("failed to write profile: %v", err)
+	}
+	loaded, err := loadTasks(profile)
+	if err != nil {
+		t.Fatalf("loadTasks got unexpected error: %v", err)
+	}
+	for _, task := range loaded {
+		if err := processor.exportTask(task); err != nil {
+			t.Fatalf("exportTask(%q) got unexpected error: %v", task.Name, err)
+		}
+	}
+	want := map[string]string{
+		"ads.list":    "DOMAIN-SUFFIX,example.net\nDOMAIN,ads.example.org\n",
+		"rest.txt":    ".example.com\n.example.io\nads.example.org\n",
+		"all-ads.txt": ".example.net\nads.example.org\n",
+		"not-cn.txt":  ".example.io\n",
+		"first.hosts": "0.0.0.0 example.com\n0.0.0.0 www.example.com\n0.0.0.0 ads.example.org\n",
+	}
+	for name, content := range want {
+		got, err := os.ReadFile(filepath.Join(*outputDir, name))
+		if err != nil {
+			t.Fatalf("failed to read %q: %v", name, err)
+		}
+		if string(got) != content {
+			t.Errorf("exportTask(%q) = %q, want %q", name, got, content)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(*outputDir, "all.srs")); err != nil {
+		t.Errorf("exportTask(\"all.srs\") did not write the file: %v", err)
+	}
+
+	for _, tasks := range []string{
+		`[{"name": "x", "mode": "all", "format": "unknown"}]`,
+		`[{"name": "x", "mode": "all", "format": "surge", "options": {"version": "1"}}]`,
+		`[{"name": "x", "mode": "all", "attrs": "@ads"}]`,
+		`[{"name": "x", "mode": "all", "format": "surge", "attrs": "ads"}]`,
+		`[{"name": "x", "mode": "all", "format": "surge", "attrs": "@ads@-ads"}]`,
+		`[{"name": "x", "mode": "all", "groups": {"a": ["cn"]}}]`,
+		`[{"name": "x", "mode": "all", "format": "split", "groups": {"a b": ["cn"]}}]`,
+		`[{"name": "x", "mode": "all", "format": "split", "attrs": "@ads"}]`,
+	} {
+		if err := os.WriteFile(profile, []byte(tasks), 0644); err != nil {
+			t.Fatalf("failed to write profile: %v", err)
+		}
+		if _, err := loadTasks(profile); err == nil {
+			t.Errorf("loadTasks(%s) = nil error, want error", tasks)
+		}
+	}
+}
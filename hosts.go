@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/v2fly/domain-list-community/internal/dlc"
+)
+
+const defaultHostsAddress = "0.0.0.0"
+
+// writeHosts writes the lists as a hosts file, which maps the hosts of full
+// type rules to the `address` option. As hosts never match subdomains, a
+// domain type rule becomes the domain itself and its subdomains among the
+// known domains of the list, and the coverage lost is reported.
+func writeHosts(w io.Writer, lists []*ExportList, opts ExportOptions) error {
+	seen := make(map[string]bool)
+	for _, list := range lists {
+		rawAddr := opts.getFor("address", list.Name, defaultHostsAddress)
+		addr, err := netip.ParseAddr(rawAddr)
+		if err != nil {
+			return fmt.Errorf("invalid address %q of list %q: %w", rawAddr, list.Name, err)
+		}
+
+		entries := stripAttrs(list.Entries)
+		subdomains := make(map[string][]string)
+		for _, entry := range entries {
+			if entry.Type == dlc.RuleTypeDomain {
+				subdomains[entry.Value] = nil
+			}
+		}
+		for _, known := range list.Known {
+			for parent := known; ; {
+				var found bool
+				if _, parent, found = strings.Cut(parent, "."); !found {
+					break
+				}
+				if domains, exist := subdomains[parent]; exist {
+					subdomains[parent] = append(domains, known)
+				}
+			}
+		}
+
+		var dropped, approximated []*Entry
+		for _, entry := range entries {
+			hosts := []string{entry.Value}
+			switch entry.Type {
+			case dlc.RuleTypeDomain:
+				approximated = append(approximated, entry)
+				hosts = append(hosts, subdomains[entry.Value]...)
+				slices.Sort(hosts[1:])
+			case dlc.RuleTypeFullDomain:
+			default:
+				dropped = append(dropped, entry)
+				continue
+			}
+			for _, host := range hosts {
+				if !seen[host] {
+					seen[host] = true
+					fmt.Fprintf(w, "%s %s\n", addr, host)
+				}
+			}
+		}
+		warnDropped("hosts", []*ExportList{list}, dropped)
+		warnLossy([]*ExportList{list}, approximated, "approximated by the domains and their known subdomains in format hosts")
+	}
+	return nil
+}
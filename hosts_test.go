@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteHosts(t *testing.T) {
+	ads := testExportList(t, "category-ads",
+		"domain:example.com",
+		"full:ads.example.org",
+		"keyword:tracker", // Dropped
+	)
+	ads.Known = []string{"a.example.com", "b.a.example.com", "example.com", "example.org", "notexample.com"}
+	private := testExportList(t, "private",
+		"full:ads.example.org", // Duplicated
+		"full:router.example.net",
+	)
+	var buf bytes.Buffer
+	if err := writeHosts(&buf, []*ExportList{ads, private}, ExportOptions{"address:private": "::"}); err != nil {
+		t.Fatalf("writeHosts got unexpected error: %v", err)
+	}
+	want := "0.0.0.0 example.com\n" +
+		"0.0.0.0 a.example.com\n" +
+		"0.0.0.0 b.a.example.com\n" +
+		"0.0.0.0 ads.example.org\n" +
+		":: router.example.net\n"
+	if got := buf.String(); got != want {
+		t.Errorf("writeHosts() = %q, want %q", got, want)
+	}
+
+	if err := writeHosts(new(bytes.Buffer), []*ExportList{ads}, ExportOptions{"address": "localhost"}); err == nil {
+		t.Error("writeHosts() with address \"localhost\" = nil error, want invalid address error")
+	}
+}
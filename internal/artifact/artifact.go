@@ -0,0 +1,185 @@
+// Package artifact writes the compressed variants of generated files and the
+// checksum sidecars of them, like the ones of gzip, xz, zstd, zip and
+// sha256sum, so that every pipeline releases the same artifacts.
+package artifact
+
+import (
+	"archive/zip"
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// Compressions by their file extensions
+var compressions = map[string]func(w io.Writer, name string, modTime time.Time) (io.WriteCloser, error){
+	"gz":  newGzipWriter,
+	"xz":  newXZWriter,
+	"zst": newZstdWriter,
+	"zip": newZipWriter,
+}
+
+// Checksums by the extensions of their sidecars without "sum"
+var checksums = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+type Options struct {
+	Compressions []string  // Extensions of the compressed variants, e.g. "gz"
+	Checksums    []string  // Algorithms of the checksum sidecars, e.g. "sha256"
+	ModTime      time.Time // Modification time recorded in gz and zip files
+}
+
+// ParseOptions parses the compressions and checksums separated by ',' comma.
+func ParseOptions(rawCompressions, rawChecksums string, modTime time.Time) (*Options, error) {
+	opts := &Options{ModTime: modTime}
+	for raw := range strings.SplitSeq(rawCompressions, ",") {
+		if ext := strings.ToLower(strings.TrimSpace(raw)); ext != "" {
+			if _, ok := compressions[ext]; !ok {
+				return nil, fmt.Errorf("unknown compression: %q", raw)
+			}
+			if !slices.Contains(opts.Compressions, ext) {
+				opts.Compressions = append(opts.Compressions, ext)
+			}
+		}
+	}
+	for raw := range strings.SplitSeq(rawChecksums, ",") {
+		if algo := strings.ToLower(strings.TrimSpace(raw)); algo != "" {
+			if _, ok := checksums[algo]; !ok {
+				return nil, fmt.Errorf("unknown checksum: %q", raw)
+			}
+			if !slices.Contains(opts.Checksums, algo) {
+				opts.Checksums = append(opts.Checksums, algo)
+			}
+		}
+	}
+	return opts, nil
+}
+
+// Write writes the compressed variants of the file, like `dlc.dat.xz`, and the
+// checksum sidecars of the file and its variants, like `dlc.dat.sha256sum`,
+// next to the file. It returns the paths of the written files.
+func (opts *Options) Write(path string) ([]string, error) {
+	var written []string
+	paths := []string{path}
+	for _, ext := range opts.Compressions {
+		cpath := path + "." + ext
+		if err := compressFile(path, cpath, compressions[ext], opts.ModTime); err != nil {
+			return written, fmt.Errorf("failed to write %q: %w", cpath, err)
+		}
+		written = append(written, cpath)
+		paths = append(paths, cpath)
+	}
+	for _, algo := range opts.Checksums {
+		for _, p := range paths {
+			spath := p + "." + algo + "sum"
+			if err := writeChecksum(p, spath, checksums[algo]); err != nil {
+				return written, fmt.Errorf("failed to write %q: %w", spath, err)
+			}
+			written = append(written, spath)
+		}
+	}
+	return written, nil
+}
+
+func compressFile(src, dst string, newWriter func(w io.Writer, name string, modTime time.Time) (io.WriteCloser, error), modTime time.Time) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(dst)
+		}
+	}()
+	bw := bufio.NewWriter(out)
+	cw, err := newWriter(bw, filepath.Base(src), modTime)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(cw, in); err != nil {
+		return err
+	}
+	if err := cw.Close(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// writeChecksum writes the checksum of the file in the format of coreutils,
+// like `sha256sum dlc.dat`, which is the hex digest and the file name
+// separated by two spaces.
+func writeChecksum(src, dst string, newHash func() hash.Hash) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	h := newHash()
+	if _, err := io.Copy(h, in); err != nil {
+		return err
+	}
+	line := hex.EncodeToString(h.Sum(nil)) + "  " + filepath.Base(src) + "\n"
+	return os.WriteFile(dst, []byte(line), 0644)
+}
+
+func newGzipWriter(w io.Writer, name string, modTime time.Time) (io.WriteCloser, error) {
+	gw, err := gzip.NewWriterLevel(w, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	gw.Name, gw.ModTime = name, modTime
+	return gw, nil
+}
+
+func newXZWriter(w io.Writer, _ string, _ time.Time) (io.WriteCloser, error) {
+	return xz.NewWriter(w)
+}
+
+func newZstdWriter(w io.Writer, _ string, _ time.Time) (io.WriteCloser, error) {
+	return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.SpeedBestCompression))
+}
+
+// zipWriter is a zip archive of a single file.
+type zipWriter struct {
+	io.Writer
+	zw *zip.Writer
+}
+
+func newZipWriter(w io.Writer, name string, modTime time.Time) (io.WriteCloser, error) {
+	zw := zip.NewWriter(w)
+	zw.RegisterCompressor(zip.Deflate, func(w io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(w, flate.BestCompression)
+	})
+	fw, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modTime})
+	if err != nil {
+		return nil, err
+	}
+	return &zipWriter{Writer: fw, zw: zw}, nil
+}
+
+func (w *zipWriter) Close() error {
+	return w.zw.Close()
+}
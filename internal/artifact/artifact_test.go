@@ -0,0 +1,142 @@
+package artifact
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+func TestParseOptions(t *testing.T) {
+	opts, err := ParseOptions(" gz, XZ,gz,", "sha256,SHA512", time.Time{})
+	if err != nil {
+		t.Fatalf("ParseOptions got unexpected error: %v", err)
+	}
+	if want := []string{"gz", "xz"}; !slices.Equal(opts.Compressions, want) {
+		t.Errorf("ParseOptions() compressions = %v, want %v", opts.Compressions, want)
+	}
+	if want := []string{"sha256", "sha512"}; !slices.Equal(opts.Checksums, want) {
+		t.Errorf("ParseOptions() checksums = %v, want %v", opts.Checksums, want)
+	}
+	if _, err := ParseOptions("bz2", "", time.Time{}); err == nil {
+		t.Error("ParseOptions(\"bz2\", \"\") = nil error, want unknown compression error")
+	}
+	if _, err := ParseOptions("", "md5", time.Time{}); err == nil {
+		t.Error("ParseOptions(\"\", \"md5\") = nil error, want unknown checksum error")
+	}
+}
+
+func readZip(t *testing.T, data []byte) io.Reader {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("failed to open zip: %v", err)
+	}
+	if len(zr.File) != 1 || zr.File[0].Name != "dlc.dat" {
+		t.Fatalf("zip has files %v, want dlc.dat only", zr.File)
+	}
+	rc, err := zr.File[0].Open()
+	if err != nil {
+		t.Fatalf("failed to open dlc.dat in zip: %v", err)
+	}
+	return rc
+}
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dlc.dat")
+	content := bytes.Repeat([]byte("domain:example.com\n"), 1000)
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+	opts, err := ParseOptions("gz,xz,zst,zip", "sha256,sha512", time.Unix(1700000000, 0).UTC())
+	if err != nil {
+		t.Fatalf("ParseOptions got unexpected error: %v", err)
+	}
+	written, err := opts.Write(path)
+	if err != nil {
+		t.Fatalf("Write got unexpected error: %v", err)
+	}
+	if len(written) != 4+5*2 {
+		t.Errorf("Write() wrote %d files, want %d: %v", len(written), 4+5*2, written)
+	}
+
+	readers := map[string]func(data []byte) io.Reader{
+		"gz": func(data []byte) io.Reader {
+			r, err := gzip.NewReader(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("failed to open gz: %v", err)
+			}
+			if r.Name != "dlc.dat" || r.ModTime.Unix() != 1700000000 {
+				t.Errorf("gz header = %q %v, want dlc.dat at 1700000000", r.Name, r.ModTime)
+			}
+			return r
+		},
+		"xz": func(data []byte) io.Reader {
+			r, err := xz.NewReader(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("failed to open xz: %v", err)
+			}
+			return r
+		},
+		"zst": func(data []byte) io.Reader {
+			r, err := zstd.NewReader(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("failed to open zst: %v", err)
+			}
+			return r
+		},
+		"zip": func(data []byte) io.Reader { return readZip(t, data) },
+	}
+	for ext, newReader := range readers {
+		data, err := os.ReadFile(path + "." + ext)
+		if err != nil {
+			t.Fatalf("failed to read %s variant: %v", ext, err)
+		}
+		got, err := io.ReadAll(newReader(data))
+		if err != nil {
+			t.Errorf("failed to decompress %s variant: %v", ext, err)
+			continue
+		}
+		if !bytes.Equal(got, content) {
+			t.Errorf("%s variant decompressed to %d bytes, want %d", ext, len(got), len(content))
+		}
+	}
+
+	for _, name := range []string{"dlc.dat", "dlc.dat.gz", "dlc.dat.xz", "dlc.dat.zst", "dlc.dat.zip"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("failed to read %q: %v", name, err)
+		}
+		sum256, sum512 := sha256.Sum256(data), sha512.Sum512(data)
+		for ext, sum := range map[string][]byte{"sha256sum": sum256[:], "sha512sum": sum512[:]} {
+			got, err := os.ReadFile(filepath.Join(dir, name+"."+ext))
+			if err != nil {
+				t.Fatalf("failed to read %s of %q: %v", ext, name, err)
+			}
+			if want := hex.EncodeToString(sum) + "  " + name + "\n"; string(got) != want {
+				t.Errorf("%s of %q = %q, want %q", ext, name, got, want)
+			}
+		}
+	}
+
+	// The variants are reproducible with the same modification time
+	first, _ := os.ReadFile(path + ".zip.sha256sum")
+	if _, err := opts.Write(path); err != nil {
+		t.Fatalf("Write got unexpected error: %v", err)
+	}
+	if second, _ := os.ReadFile(path + ".zip.sha256sum"); !bytes.Equal(first, second) {
+		t.Errorf("Write() is not reproducible: %q != %q", first, second)
+	}
+}
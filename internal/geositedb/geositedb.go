@@ -0,0 +1,225 @@
+// Package geositedb reads and writes the geosite.db database of sing-box
+// before 1.8, which maps codes like `google` and `google@ads` to their items.
+package geositedb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	router "github.com/v2fly/v2ray-core/v5/app/router/routercommon"
+)
+
+// Types of items
+const (
+	TypeDomain uint8 = iota
+	TypeDomainSuffix
+	TypeDomainKeyword
+	TypeDomainRegex
+)
+
+const version = 0
+
+type Item struct {
+	Type  uint8
+	Value string
+}
+
+// FromSites returns the items of the codes of the sites. A domain type rule
+// becomes the domain and the suffix of its subdomains. The rules with an
+// attribute are also the items of the sub-code like `google@ads`.
+func FromSites(sites []*router.GeoSite) map[string][]Item {
+	codes := make(map[string][]Item)
+	for _, site := range sites {
+		code := strings.ToLower(site.CountryCode)
+		for _, domain := range site.Domain {
+			var items []Item
+			switch domain.Type {
+			case router.Domain_RootDomain:
+				items = []Item{{TypeDomain, domain.Value}, {TypeDomainSuffix, "." + domain.Value}}
+			case router.Domain_Full:
+				items = []Item{{TypeDomain, domain.Value}}
+			case router.Domain_Plain:
+				items = []Item{{TypeDomainKeyword, domain.Value}}
+			case router.Domain_Regex:
+				items = []Item{{TypeDomainRegex, domain.Value}}
+			}
+			codes[code] = append(codes[code], items...)
+			for _, attr := range domain.Attribute {
+				subCode := code + "@" + attr.Key
+				codes[subCode] = append(codes[subCode], items...)
+			}
+		}
+	}
+	return codes
+}
+
+// ToSites returns the sites of the codes written by FromSites, with the
+// attributes restored from the sub-codes.
+func ToSites(codes map[string][]Item) []*router.GeoSite {
+	var sites []*router.GeoSite
+	siteIdx := make(map[string]int)
+	for _, code := range slices.Sorted(maps.Keys(codes)) {
+		if !strings.Contains(code, "@") {
+			siteIdx[code] = len(sites)
+			sites = append(sites, &router.GeoSite{CountryCode: strings.ToUpper(code), Domain: toDomains(codes[code])})
+		}
+	}
+	for _, subCode := range slices.Sorted(maps.Keys(codes)) {
+		code, attr, ok := strings.Cut(subCode, "@")
+		idx, exist := siteIdx[code]
+		if !ok || !exist {
+			continue
+		}
+		attributed := make(map[string]bool)
+		for _, domain := range toDomains(codes[subCode]) {
+			attributed[domain.Type.String()+":"+domain.Value] = true
+		}
+		for _, domain := range sites[idx].Domain {
+			if attributed[domain.Type.String()+":"+domain.Value] {
+				domain.Attribute = append(domain.Attribute, &router.Domain_Attribute{
+					Key:        attr,
+					TypedValue: &router.Domain_Attribute_BoolValue{BoolValue: true},
+				})
+			}
+		}
+	}
+	return sites
+}
+
+// toDomains returns the rules of the items, where a domain followed by the
+// suffix of its subdomains is a domain type rule.
+func toDomains(items []Item) []*router.Domain {
+	domains := make([]*router.Domain, 0, len(items))
+	for i := 0; i < len(items); i++ {
+		item := items[i]
+		domain := &router.Domain{Value: item.Value}
+		switch item.Type {
+		case TypeDomain:
+			domain.Type = router.Domain_Full
+			if i+1 < len(items) && items[i+1] == (Item{TypeDomainSuffix, "." + item.Value}) {
+				domain.Type = router.Domain_RootDomain
+				i++
+			}
+		case TypeDomainSuffix:
+			domain.Type, domain.Value = router.Domain_RootDomain, strings.TrimPrefix(item.Value, ".")
+		case TypeDomainKeyword:
+			domain.Type = router.Domain_Plain
+		case TypeDomainRegex:
+			domain.Type = router.Domain_Regex
+		}
+		domains = append(domains, domain)
+	}
+	return domains
+}
+
+// Write writes the database of the codes, which are sorted. Codes without
+// items are skipped, as readers read the items of a code by its length.
+func Write(w io.Writer, codes map[string][]Item) error {
+	var content []byte
+	var meta []byte
+	count := 0
+	for _, code := range slices.Sorted(maps.Keys(codes)) {
+		items := codes[code]
+		if len(items) == 0 {
+			continue
+		}
+		count++
+		meta = appendString(meta, code)
+		meta = binary.AppendUvarint(meta, uint64(len(content)))
+		meta = binary.AppendUvarint(meta, uint64(len(items)))
+		for _, item := range items {
+			content = append(content, item.Type)
+			content = appendString(content, item.Value)
+		}
+	}
+	header := binary.AppendUvarint([]byte{version}, uint64(count))
+	for _, b := range [][]byte{header, meta, content} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Read reads the items of all the codes of a database.
+func Read(r io.Reader) (map[string][]Item, error) {
+	br := bufio.NewReader(r)
+	if v, err := br.ReadByte(); err != nil {
+		return nil, err
+	} else if v != version {
+		return nil, fmt.Errorf("unknown version: %d", v)
+	}
+	count, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the number of codes: %w", err)
+	}
+	type metadata struct {
+		code          string
+		index, length uint64
+	}
+	metas := make([]metadata, count)
+	for i := range metas {
+		if metas[i].code, err = readString(br); err != nil {
+			return nil, fmt.Errorf("failed to read code: %w", err)
+		}
+		if metas[i].index, err = binary.ReadUvarint(br); err != nil {
+			return nil, fmt.Errorf("failed to read index of code %q: %w", metas[i].code, err)
+		}
+		if metas[i].length, err = binary.ReadUvarint(br); err != nil {
+			return nil, fmt.Errorf("failed to read length of code %q: %w", metas[i].code, err)
+		}
+	}
+	content, err := io.ReadAll(br)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make(map[string][]Item, count)
+	for _, meta := range metas {
+		if meta.index > uint64(len(content)) {
+			return nil, fmt.Errorf("index %d of code %q out of range", meta.index, meta.code)
+		}
+		cr := bytes.NewReader(content[meta.index:])
+		items := make([]Item, 0, min(meta.length, uint64(len(content))))
+		for range meta.length {
+			typ, err := cr.ReadByte()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read item of code %q: %w", meta.code, err)
+			}
+			value, err := readString(cr)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read item of code %q: %w", meta.code, err)
+			}
+			items = append(items, Item{typ, value})
+		}
+		codes[meta.code] = items
+	}
+	return codes, nil
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+func readString(r io.ByteReader) (string, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return "", err
+	}
+	b := make([]byte, 0, min(n, 4096))
+	for range n {
+		c, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		b = append(b, c)
+	}
+	return string(b), nil
+}
@@ -0,0 +1,81 @@
+package geositedb
+
+import (
+	"bytes"
+	"maps"
+	"slices"
+	"testing"
+
+	router "github.com/v2fly/v2ray-core/v5/app/router/routercommon"
+	"google.golang.org/protobuf/proto"
+)
+
+func testDomain(typ router.Domain_Type, value string, attrs ...string) *router.Domain {
+	domain := &router.Domain{Type: typ, Value: value}
+	for _, attr := range attrs {
+		domain.Attribute = append(domain.Attribute, &router.Domain_Attribute{
+			Key:        attr,
+			TypedValue: &router.Domain_Attribute_BoolValue{BoolValue: true},
+		})
+	}
+	return domain
+}
+
+func TestRoundTrip(t *testing.T) {
+	sites := []*router.GeoSite{
+		{CountryCode: "CN", Domain: []*router.Domain{
+			testDomain(router.Domain_RootDomain, "example.cn"),
+			testDomain(router.Domain_RootDomain, "example.com"),
+			testDomain(router.Domain_Full, "example.com", "cn"),
+			testDomain(router.Domain_Plain, "baidu"),
+		}},
+		{CountryCode: "GOOGLE", Domain: []*router.Domain{
+			testDomain(router.Domain_RootDomain, "doubleclick.net", "ads", "cn"),
+			testDomain(router.Domain_Full, "www.google.com"),
+			testDomain(router.Domain_Regex, `^ads\d\.google\.com$`, "ads"),
+		}},
+	}
+	codes := FromSites(sites)
+	wantCodes := []string{"cn", "cn@cn", "google", "google@ads", "google@cn"}
+	if got := slices.Sorted(maps.Keys(codes)); !slices.Equal(got, wantCodes) {
+		t.Errorf("FromSites() codes = %q, want %q", got, wantCodes)
+	}
+	wantItems := []Item{{TypeDomain, "doubleclick.net"}, {TypeDomainSuffix, ".doubleclick.net"}, {TypeDomainRegex, `^ads\d\.google\.com$`}}
+	if got := codes["google@ads"]; !slices.Equal(got, wantItems) {
+		t.Errorf("FromSites() google@ads = %v, want %v", got, wantItems)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, codes); err != nil {
+		t.Fatalf("Write got unexpected error: %v", err)
+	}
+	read, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read got unexpected error: %v", err)
+	}
+	got := ToSites(read)
+	if len(got) != len(sites) {
+		t.Fatalf("ToSites() = %d sites, want %d", len(got), len(sites))
+	}
+	for i := range sites {
+		if !proto.Equal(got[i], sites[i]) {
+			t.Errorf("ToSites()[%d] = %v, want %v", i, got[i], sites[i])
+		}
+	}
+}
+
+func TestReadInvalid(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, map[string][]Item{"cn": {{TypeDomain, "example.cn"}}, "empty": nil}); err != nil {
+		t.Fatalf("Write got unexpected error: %v", err)
+	}
+	data := buf.Bytes()
+	if codes, err := Read(bytes.NewReader(data)); err != nil || len(codes) != 1 {
+		t.Errorf("Read() = %v, %v, want the code without the empty one", codes, err)
+	}
+	for _, invalid := range [][]byte{{1, 0}, data[:len(data)-1], data[:3]} {
+		if _, err := Read(bytes.NewReader(invalid)); err == nil {
+			t.Errorf("Read(%v) = nil error, want error", invalid)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Package manifest reads and writes manifest.json, which describes the files
+// generated into an output directory by one or more runs of the generator and
+// datdump, so that mirrors can verify and diff releases.
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/v2fly/domain-list-community/internal/artifact"
+	"github.com/v2fly/domain-list-community/internal/dlc"
+	router "github.com/v2fly/v2ray-core/v5/app/router/routercommon"
+)
+
+// Kinds of the files derived from another file in the manifest
+const (
+	KindCompressed string = "compressed"
+	KindChecksum   string = "checksum"
+)
+
+type File struct {
+	Name   string         `json:"name"`
+	Kind   string         `json:"kind"` // "dat", "geositedb", an export format, "sqlite", "listreport", "yml" of datdump, KindCompressed or KindChecksum
+	Size   int64          `json:"size"`
+	SHA256 string         `json:"sha256"`
+	Source string         `json:"source,omitempty"` // File which a compressed variant or checksum sidecar is derived from
+	Lists  int            `json:"lists,omitempty"`  // Number of geosites or lists in the file
+	Rules  map[string]int `json:"rules,omitempty"`  // Number of rules by type, before the conversion of export formats
+}
+
+type Source struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+}
+
+type Manifest struct {
+	Generator string  `json:"generator"`
+	Version   string  `json:"version"`
+	Built     string  `json:"built"` // RFC 3339 time of the build
+	Data      *Source `json:"data"`
+	Profile   *Source `json:"profile,omitempty"`
+	Files     []*File `json:"files"`
+}
+
+// HashFile returns the size and hex SHA-256 digest of the file.
+func HashFile(path string) (int64, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer file.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, file)
+	if err != nil {
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Read reads the manifest at the path.
+func Read(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := new(Manifest)
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal: %w", err)
+	}
+	return m, nil
+}
+
+// AddFile adds the generated file in the directory, and its compressed
+// variants and checksum sidecars written by the artifact options, to the
+// manifest if it is not nil. A file generated again replaces the former one.
+func (m *Manifest) AddFile(dir, filename, kind string, lists int, rules map[string]int, artifacts *artifact.Options) error {
+	if m == nil {
+		return nil
+	}
+	files := []*File{{Name: filename, Kind: kind, Lists: lists, Rules: rules}}
+	sources := []string{filename}
+	if artifacts != nil {
+		for _, ext := range artifacts.Compressions {
+			files = append(files, &File{Name: filename + "." + ext, Kind: KindCompressed, Source: filename})
+			sources = append(sources, filename+"."+ext)
+		}
+		for _, algo := range artifacts.Checksums {
+			for _, source := range sources {
+				files = append(files, &File{Name: source + "." + algo + "sum", Kind: KindChecksum, Source: source})
+			}
+		}
+	}
+	for _, file := range files {
+		var err error
+		if file.Size, file.SHA256, err = HashFile(filepath.Join(dir, file.Name)); err != nil {
+			return fmt.Errorf("failed to hash %q: %w", file.Name, err)
+		}
+	}
+	m.Files = slices.DeleteFunc(m.Files, func(file *File) bool {
+		return slices.ContainsFunc(files, func(f *File) bool { return f.Name == file.Name })
+	})
+	m.Files = append(m.Files, files...)
+	return nil
+}
+
+// Write writes the manifest to the path, with the files sorted by their
+// names. It is written directly instead of with artifacts, since it would not
+// list its own compressed variants and checksum sidecars.
+func (m *Manifest) Write(path string) (err error) {
+	slices.SortFunc(m.Files, func(a, b *File) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	enc := json.NewEncoder(file)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m)
+}
+
+// CountRules returns the number of rules of the sites by type.
+func CountRules(sites []*router.GeoSite) map[string]int {
+	counts := make(map[string]int)
+	for _, site := range sites {
+		for _, domain := range site.Domain {
+			switch domain.Type {
+			case router.Domain_RootDomain:
+				counts[dlc.RuleTypeDomain]++
+			case router.Domain_Full:
+				counts[dlc.RuleTypeFullDomain]++
+			case router.Domain_Plain:
+				counts[dlc.RuleTypeKeyword]++
+			case router.Domain_Regex:
+				counts[dlc.RuleTypeRegexp]++
+			}
+		}
+	}
+	return counts
+}
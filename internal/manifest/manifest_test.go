@@ -0,0 +1,70 @@
+package manifest
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	router "github.com/v2fly/v2ray-core/v5/app/router/routercommon"
+)
+
+func TestReadWrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "manifest.json")
+	if _, err := Read(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Read() of missing manifest got error %v, want fs.ErrNotExist", err)
+	}
+	for _, name := range []string{"b.dat", "a.yml"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatalf("failed to write %q: %v", name, err)
+		}
+	}
+
+	m := &Manifest{Generator: "test", Data: &Source{Path: "data", SHA256: "00"}}
+	if err := m.AddFile(dir, "b.dat", "dat", 2, map[string]int{"domain": 3}, nil); err != nil {
+		t.Fatalf("AddFile got unexpected error: %v", err)
+	}
+	if err := m.Write(path); err != nil {
+		t.Fatalf("Write got unexpected error: %v", err)
+	}
+	got, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read got unexpected error: %v", err)
+	}
+	if err := got.AddFile(dir, "a.yml", "yml", 1, nil, nil); err != nil {
+		t.Fatalf("AddFile got unexpected error: %v", err)
+	}
+	if err := got.AddFile(dir, "missing", "yml", 1, nil, nil); err == nil {
+		t.Error("AddFile() of missing file = nil error, want error")
+	}
+	if err := got.Write(path); err != nil {
+		t.Fatalf("Write got unexpected error: %v", err)
+	}
+	if got, err = Read(path); err != nil {
+		t.Fatalf("Read got unexpected error: %v", err)
+	}
+	if got.Generator != "test" || got.Data.SHA256 != "00" || len(got.Files) != 2 {
+		t.Fatalf("Read() = %+v, want the header and 2 files", got)
+	}
+	if got.Files[0].Name != "a.yml" || got.Files[1].Name != "b.dat" || got.Files[1].Lists != 2 || got.Files[1].Rules["domain"] != 3 {
+		t.Errorf("Read() files = %+v %+v, want a.yml and b.dat with 2 lists and 3 domain rules", got.Files[0], got.Files[1])
+	}
+
+	var nilManifest *Manifest
+	if err := nilManifest.AddFile(dir, "missing", "dat", 0, nil, nil); err != nil {
+		t.Errorf("AddFile() of nil manifest = %v, want nil", err)
+	}
+}
+
+func TestCountRules(t *testing.T) {
+	sites := []*router.GeoSite{
+		{Domain: []*router.Domain{{Type: router.Domain_RootDomain}, {Type: router.Domain_Full}}},
+		{Domain: []*router.Domain{{Type: router.Domain_RootDomain}, {Type: router.Domain_Plain}, {Type: router.Domain_Regex}}},
+	}
+	got := CountRules(sites)
+	if got["domain"] != 2 || got["full"] != 1 || got["keyword"] != 1 || got["regexp"] != 1 || len(got) != 4 {
+		t.Errorf("CountRules() = %v, want 2 domain, 1 full, 1 keyword and 1 regexp rules", got)
+	}
+}
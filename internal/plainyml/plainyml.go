@@ -0,0 +1,79 @@
+// Package plainyml writes geosites as plain YAML, with a rule per line like
+// `domain:example.com:@ads,@cn`, for people to read and diff them.
+package plainyml
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/v2fly/domain-list-community/internal/dlc"
+	router "github.com/v2fly/v2ray-core/v5/app/router/routercommon"
+)
+
+// WriteRule writes the domain as a plain rule.
+func WriteRule(b *strings.Builder, d *router.Domain) error {
+	switch d.Type {
+	case router.Domain_RootDomain:
+		b.WriteString(dlc.RuleTypeDomain)
+	case router.Domain_Full:
+		b.WriteString(dlc.RuleTypeFullDomain)
+	case router.Domain_Plain:
+		b.WriteString(dlc.RuleTypeKeyword)
+	case router.Domain_Regex:
+		b.WriteString(dlc.RuleTypeRegexp)
+	default:
+		return fmt.Errorf("invalid rule type: %+v", d.Type)
+	}
+	b.WriteByte(':')
+	b.WriteString(d.Value)
+	for i, attr := range d.Attribute {
+		if i == 0 {
+			b.WriteByte(':')
+		} else {
+			b.WriteByte(',')
+		}
+		b.WriteByte('@')
+		b.WriteString(attr.Key)
+	}
+	return nil
+}
+
+// WriteList writes the rules of a list under its name.
+func WriteList(w io.Writer, name string, domains []*router.Domain) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%q:\n", name)
+	var b strings.Builder
+	b.Grow(64)
+	for _, d := range domains {
+		b.Reset()
+		if err := WriteRule(&b, d); err != nil {
+			return err
+		}
+		fmt.Fprintf(bw, "  - %q\n", b.String())
+	}
+	return bw.Flush()
+}
+
+// WriteAll writes the sites in order, each with its lowercase name, its
+// number of rules and its rules.
+func WriteAll(w io.Writer, sites []*router.GeoSite) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("lists:\n")
+	var b strings.Builder
+	b.Grow(64)
+	for _, site := range sites {
+		fmt.Fprintf(bw, "  - name: %q\n", strings.ToLower(site.CountryCode))
+		fmt.Fprintf(bw, "    length: %d\n", len(site.Domain))
+		bw.WriteString("    rules:\n")
+		for _, d := range site.Domain {
+			b.Reset()
+			if err := WriteRule(&b, d); err != nil {
+				return err
+			}
+			fmt.Fprintf(bw, "      - %q\n", b.String())
+		}
+	}
+	return bw.Flush()
+}
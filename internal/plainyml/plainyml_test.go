@@ -0,0 +1,82 @@
+package plainyml
+
+import (
+	"bytes"
+	"testing"
+
+	router "github.com/v2fly/v2ray-core/v5/app/router/routercommon"
+)
+
+func testDomain(typ router.Domain_Type, value string, attrs ...string) *router.Domain {
+	domain := &router.Domain{Type: typ, Value: value}
+	for _, attr := range attrs {
+		domain.Attribute = append(domain.Attribute, &router.Domain_Attribute{
+			Key:        attr,
+			TypedValue: &router.Domain_Attribute_BoolValue{BoolValue: true},
+		})
+	}
+	return domain
+}
+
+func TestWrite(t *testing.T) {
+	sites := []*router.GeoSite{
+		{CountryCode: "CN", Domain: []*router.Domain{
+			testDomain(router.Domain_RootDomain, "example.cn"),
+			testDomain(router.Domain_Full, "example.com", "ads", "cn"),
+		}},
+		{CountryCode: "GOOGLE", Domain: []*router.Domain{
+			testDomain(router.Domain_Plain, "google"),
+			testDomain(router.Domain_Regex, `^ads\d\.google\.com$`, "ads"),
+		}},
+	}
+	tests := []struct {
+		name  string
+		write func(w *bytes.Buffer) error
+		want  string
+	}{
+		{
+			name:  "all",
+			write: func(w *bytes.Buffer) error { return WriteAll(w, sites) },
+			want: "lists:\n" +
+				"  - name: \"cn\"\n" +
+				"    length: 2\n" +
+				"    rules:\n" +
+				"      - \"domain:example.cn\"\n" +
+				"      - \"full:example.com:@ads,@cn\"\n" +
+				"  - name: \"google\"\n" +
+				"    length: 2\n" +
+				"    rules:\n" +
+				"      - \"keyword:google\"\n" +
+				"      - \"regexp:^ads\\\\d\\\\.google\\\\.com$:@ads\"\n",
+		},
+		{
+			name:  "list",
+			write: func(w *bytes.Buffer) error { return WriteList(w, "google", sites[1].Domain) },
+			want: "\"google\":\n" +
+				"  - \"keyword:google\"\n" +
+				"  - \"regexp:^ads\\\\d\\\\.google\\\\.com$:@ads\"\n",
+		},
+		{
+			name:  "invalid type",
+			write: func(w *bytes.Buffer) error { return WriteList(w, "bad", []*router.Domain{{Type: 9, Value: "x"}}) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := tt.write(&buf)
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// jsonRule is a rule in JSON exports, whose fields are described by the JSON
+// Schemas in the schemas directory.
+type jsonRule struct {
+	List   string      `json:"list,omitempty"` // Only in NDJSON streams
+	Type   string      `json:"type"`
+	Value  string      `json:"value"`
+	Attrs  []string    `json:"attrs"`
+	Source *jsonSource `json:"source,omitempty"`
+}
+
+// jsonSource is the provenance of a rule.
+type jsonSource struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+type jsonList struct {
+	Name  string      `json:"name"`
+	Rules []*jsonRule `json:"rules"`
+}
+
+type jsonMetadata struct {
+	Generator string `json:"generator"`
+	Built     string `json:"built"` // RFC 3339 time of the build
+}
+
+type jsonDocument struct {
+	Metadata *jsonMetadata `json:"metadata,omitempty"`
+	Lists    []*jsonList   `json:"lists"`
+}
+
+// boolOption returns the boolean option of the key, or false if it is not
+// set.
+func boolOption(opts ExportOptions, key string) (bool, error) {
+	raw := opts.get(key, "false")
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s: %q", key, raw)
+	}
+	return value, nil
+}
+
+// newJSONRules returns the rules of the list, with their provenance if
+// provenance is true.
+func newJSONRules(list *ExportList, provenance bool) []*jsonRule {
+	rules := make([]*jsonRule, len(list.Entries))
+	for i, entry := range list.Entries {
+		rule := &jsonRule{Type: entry.Type, Value: entry.Value, Attrs: entry.Attrs}
+		if rule.Attrs == nil {
+			rule.Attrs = []string{}
+		}
+		if provenance && entry.File != "" {
+			rule.Source = &jsonSource{File: entry.File, Line: entry.Line}
+		}
+		rules[i] = rule
+	}
+	return rules
+}
+
+// writeJSON writes the lists as a JSON document, with the provenance of rules
+// by the `provenance` option and the metadata of the build by the `metadata`
+// option.
+func writeJSON(w io.Writer, lists []*ExportList, opts ExportOptions) error {
+	provenance, err := boolOption(opts, "provenance")
+	if err != nil {
+		return err
+	}
+	metadata, err := boolOption(opts, "metadata")
+	if err != nil {
+		return err
+	}
+	doc := &jsonDocument{Lists: make([]*jsonList, len(lists))}
+	if metadata {
+		built, err := buildTime()
+		if err != nil {
+			return err
+		}
+		doc.Metadata = &jsonMetadata{Generator: generatorHomepage, Built: built.Format(time.RFC3339)}
+	}
+	for i, list := range lists {
+		doc.Lists[i] = &jsonList{Name: list.Name, Rules: newJSONRules(list, provenance)}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// writeNDJSON writes the lists as a stream of newline delimited JSON rules,
+// each of which has the name of its list, with their provenance by the
+// `provenance` option.
+func writeNDJSON(w io.Writer, lists []*ExportList, opts ExportOptions) error {
+	provenance, err := boolOption(opts, "provenance")
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for _, list := range lists {
+		for _, rule := range newJSONRules(list, provenance) {
+			rule.List = list.Name
+			if err := enc.Encode(rule); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
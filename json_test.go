@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// validateSchema validates the value against the subset of JSON Schema used by
+// the schemas directory.
+func validateSchema(t *testing.T, schema map[string]any, value any, path string) error {
+	t.Helper()
+	if ref, ok := schema["$ref"].(string); ok {
+		return validateSchema(t, loadSchema(t, ref), value, path)
+	}
+	if typ, ok := schema["type"].(string); ok {
+		var match bool
+		switch typ {
+		case "object":
+			_, match = value.(map[string]any)
+		case "array":
+			_, match = value.([]any)
+		case "string":
+			_, match = value.(string)
+		case "integer":
+			n, isNumber := value.(float64)
+			match = isNumber && n == float64(int64(n))
+		default:
+			return fmt.Errorf("unsupported type %q in schema", typ)
+		}
+		if !match {
+			return fmt.Errorf("%s: %v is not of type %s", path, value, typ)
+		}
+	}
+	if enum, ok := schema["enum"].([]any); ok && !slices.Contains(enum, value) {
+		return fmt.Errorf("%s: %v is not one of %v", path, value, enum)
+	}
+	if object, ok := value.(map[string]any); ok {
+		properties, _ := schema["properties"].(map[string]any)
+		for _, key := range schema["required"].([]any) {
+			if _, ok := object[key.(string)]; !ok {
+				return fmt.Errorf("%s: missing required property %q", path, key)
+			}
+		}
+		for key, child := range object {
+			sub, ok := properties[key].(map[string]any)
+			if !ok {
+				if schema["additionalProperties"] == false {
+					return fmt.Errorf("%s: unexpected property %q", path, key)
+				}
+				continue
+			}
+			if err := validateSchema(t, sub, child, path+"."+key); err != nil {
+				return err
+			}
+		}
+	}
+	if array, ok := value.([]any); ok {
+		items, _ := schema["items"].(map[string]any)
+		for i, item := range array {
+			if err := validateSchema(t, items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func loadSchema(t *testing.T, name string) map[string]any {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("schemas", name))
+	if err != nil {
+		t.Fatalf("failed to read schema: %v", err)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("failed to unmarshal schema %q: %v", name, err)
+	}
+	return schema
+}
+
+func testJSONLists(t *testing.T) []*ExportList {
+	t.Helper()
+	lists := []*ExportList{
+		testExportList(t, "google", "domain:example.com", "full:ads.example.org @ads @cn", "keyword:baidu"),
+		testExportList(t, "cn@ads", `regexp:^ads\d\.example\.cn$ @ads`),
+	}
+	lists[0].Entries[0].File, lists[0].Entries[0].Line = "google", 3
+	return lists
+}
+
+func TestWriteJSON(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+	lists := testJSONLists(t)
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, lists, ExportOptions{"provenance": "true", "metadata": "true"}); err != nil {
+		t.Fatalf("writeJSON got unexpected error: %v", err)
+	}
+	var doc jsonDocument
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("failed to unmarshal writeJSON output: %v", err)
+	}
+	if doc.Metadata == nil || doc.Metadata.Built != "2023-11-14T22:13:20Z" {
+		t.Errorf("writeJSON() metadata = %+v, want built time 2023-11-14T22:13:20Z", doc.Metadata)
+	}
+	if len(doc.Lists) != 2 || doc.Lists[0].Name != "google" || doc.Lists[1].Name != "cn@ads" {
+		t.Fatalf("writeJSON() lists = %+v, want lists google and cn@ads", doc.Lists)
+	}
+	got := doc.Lists[0].Rules
+	want := []*jsonRule{
+		{Type: "domain", Value: "example.com", Attrs: []string{}, Source: &jsonSource{File: "google", Line: 3}},
+		{Type: "full", Value: "ads.example.org", Attrs: []string{"ads", "cn"}},
+		{Type: "keyword", Value: "baidu", Attrs: []string{}},
+	}
+	gotJSON, _ := json.Marshal(got)
+	wantJSON, _ := json.Marshal(want)
+	if !bytes.Equal(gotJSON, wantJSON) {
+		t.Errorf("writeJSON() rules = %s, want %s", gotJSON, wantJSON)
+	}
+
+	var value any
+	json.Unmarshal(buf.Bytes(), &value)
+	if err := validateSchema(t, loadSchema(t, "geosite.schema.json"), value, "$"); err != nil {
+		t.Errorf("writeJSON() output is invalid: %v", err)
+	}
+
+	buf.Reset()
+	if err := writeJSON(&buf, lists, nil); err != nil {
+		t.Fatalf("writeJSON got unexpected error: %v", err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte(`"metadata"`)) || bytes.Contains(buf.Bytes(), []byte(`"source"`)) {
+		t.Errorf("writeJSON() without options has metadata or provenance: %s", buf.Bytes())
+	}
+	if err := writeJSON(&buf, lists, ExportOptions{"metadata": "maybe"}); err == nil {
+		t.Error("writeJSON() with metadata \"maybe\" = nil error, want invalid metadata error")
+	}
+}
+
+func TestWriteNDJSON(t *testing.T) {
+	lists := testJSONLists(t)
+	var buf bytes.Buffer
+	if err := writeNDJSON(&buf, lists, ExportOptions{"provenance": "1"}); err != nil {
+		t.Fatalf("writeNDJSON got unexpected error: %v", err)
+	}
+	want := `{"list":"google","type":"domain","value":"example.com","attrs":[],"source":{"file":"google","line":3}}` + "\n" +
+		`{"list":"google","type":"full","value":"ads.example.org","attrs":["ads","cn"]}` + "\n" +
+		`{"list":"google","type":"keyword","value":"baidu","attrs":[]}` + "\n" +
+		`{"list":"cn@ads","type":"regexp","value":"^ads\\d\\.example\\.cn$","attrs":["ads"]}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("writeNDJSON() = %q, want %q", got, want)
+	}
+
+	schema := loadSchema(t, "geosite-rule.schema.json")
+	scanner := bufio.NewScanner(&buf)
+	for line := 1; scanner.Scan(); line++ {
+		var value any
+		if err := json.Unmarshal(scanner.Bytes(), &value); err != nil {
+			t.Fatalf("failed to unmarshal line %d: %v", line, err)
+		}
+		if err := validateSchema(t, schema, value, fmt.Sprintf("line %d", line)); err != nil {
+			t.Errorf("writeNDJSON() output is invalid: %v", err)
+		}
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	schema := loadSchema(t, "geosite-rule.schema.json")
+	for _, raw := range []string{
+		`{"type":"domain","value":"example.com"}`,
+		`{"type":"include","value":"example.com","attrs":[]}`,
+		`{"type":"domain","value":"example.com","attrs":["ads"],"source":{"file":"a","line":1.5}}`,
+		`{"type":"domain","value":"example.com","attrs":[],"extra":true}`,
+	} {
+		var value any
+		if err := json.Unmarshal([]byte(raw), &value); err != nil {
+			t.Fatalf("failed to unmarshal %s: %v", raw, err)
+		}
+		if err := validateSchema(t, schema, value, "$"); err == nil {
+			t.Errorf("validateSchema(%s) = nil error, want invalid rule error", raw)
+		}
+	}
+}
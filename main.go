@@ -5,14 +5,19 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
 	"slices"
 	"strings"
 
+	"github.com/v2fly/domain-list-community/internal/artifact"
 	"github.com/v2fly/domain-list-community/internal/dlc"
+	"github.com/v2fly/domain-list-community/internal/geositedb"
+	"github.com/v2fly/domain-list-community/internal/manifest"
 	router "github.com/v2fly/v2ray-core/v5/app/router/routercommon"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -21,14 +26,26 @@ var (
 	outputName  = flag.String("outputname", "dlc.dat", "Name of the generated dat file")
 	outputDir   = flag.String("outputdir", "./", "Directory to place all generated files")
 	datProfile  = flag.String("datprofile", "", "Path of config file used to assemble custom dats")
-	exportLists = flag.String("exportlists", "", "Lists to be flattened and exported in plaintext format, separated by ',' comma")
+	exportLists = flag.String("exportlists", "", "Lists to be flattened and exported in plaintext format, separated by ',' comma, e.g. 'cn,google@ads,cn@-ads'")
+	singboxDB   = flag.String("singboxdb", "", "Name of the sing-box geosite.db file generated along with the dat file, empty to skip")
+	splitDir    = flag.String("splitdir", "", "Name of the directory of a dat file per list generated along with the dat file, empty to skip")
+	compress    = flag.String("compress", "", "Compressed variants of every generated file, separated by ',' comma, e.g. 'gz,xz,zst,zip'")
+	checksums   = flag.String("checksums", "", "Checksum sidecars of every generated file and its compressed variants, separated by ',' comma, e.g. 'sha256,sha512'")
 )
 
+// artifacts writes the compressed variants and checksum sidecars of the
+// generated files, if any.
+var artifacts *artifact.Options
+
 type Entry struct {
 	Type  string
 	Value string
 	Attrs []string
 	Plain string
+	File  string // Name of the data file defining the entry
+	Line  int    // Line number of the entry in its data file
+
+	key string // Reversed labels of a domain or full type rule, see reversedKey
 }
 
 type Inclusion struct {
@@ -38,8 +55,10 @@ type Inclusion struct {
 }
 
 type ParsedList struct {
-	Inclusions []*Inclusion
-	Entries    []*Entry // Entries parsed from the list itself
+	HasFile     bool     // Whether the list has a data file
+	Affiliators []string // Lists adding entries into this list by affiliations
+	Inclusions  []*Inclusion
+	Entries     []*Entry // Entries parsed from the list itself
 	// The fields below are filled in by resolveList
 	Resolving    bool
 	Resolved     bool
@@ -54,12 +73,17 @@ type Processor struct {
 type GeoSites struct {
 	Sites   []*router.GeoSite
 	SiteIdx map[string]int
+	Records [][]byte // Encoded sites as records of the GeoSiteList entry field
 }
 
 type DatTask struct {
-	Name  string   `json:"name"`
-	Mode  string   `json:"mode"`
-	Lists []string `json:"lists"`
+	Name    string              `json:"name"`
+	Mode    string              `json:"mode"`
+	Lists   []string            `json:"lists"`
+	Format  string              `json:"format"`  // Export format of the lists, empty or "dat" for dat files, or "geositedb"
+	Options map[string]string   `json:"options"` // Options of the export format
+	Attrs   string              `json:"attrs"`   // Attribute filter of the exported lists, e.g. "@ads"
+	Groups  map[string][]string `json:"groups"`  // Lists written into the same dat file of split tasks, by the name of the file
 }
 
 const (
@@ -67,6 +91,10 @@ const (
 	ModeAllowlist string = "allowlist"
 	ModeDenylist  string = "denylist"
 
+	FormatDat       string = "dat"
+	FormatGeositeDB string = "geositedb" // geosite.db of sing-box before 1.8
+	FormatSplit     string = "split"     // Directory of a dat file per list or group
+
 	maxDomainLen int = 253 // Maximum length of a domain name
 	maxLabelLen  int = 63  // Maximum length of a label of a domain name
 )
@@ -121,35 +149,93 @@ func loadTasks(path string) ([]DatTask, error) {
 		default:
 			return nil, fmt.Errorf("task[%d] %q: invalid mode %q", i, t.Name, t.Mode)
 		}
+		if t.Format != FormatSplit && len(t.Groups) != 0 {
+			return nil, fmt.Errorf("task[%d] %q: groups is only supported by split tasks", i, t.Name)
+		}
+		for group := range t.Groups {
+			if !validateSiteName(strings.ToUpper(group)) {
+				return nil, fmt.Errorf("task[%d] %q: invalid group name %q", i, t.Name, group)
+			}
+		}
+		if t.Format == "" || t.Format == FormatDat || t.Format == FormatGeositeDB || t.Format == FormatSplit {
+			if t.Attrs != "" {
+				return nil, fmt.Errorf("task[%d] %q: attrs is only supported by export formats", i, t.Name)
+			}
+			continue
+		}
+		if t.Attrs != "" {
+			rawAttrs, ok := strings.CutPrefix(t.Attrs, "@")
+			if !ok {
+				return nil, fmt.Errorf("task[%d] %q: invalid attrs %q", i, t.Name, t.Attrs)
+			}
+			if _, err := parseAttrFilter(rawAttrs); err != nil {
+				return nil, fmt.Errorf("task[%d] %q: invalid attrs %q: %w", i, t.Name, t.Attrs, err)
+			}
+		}
+		lf, ok := listFormats[t.Format]
+		if !ok {
+			return nil, fmt.Errorf("task[%d] %q: invalid format %q", i, t.Name, t.Format)
+		}
+		for key := range t.Options {
+			if optName, _, _ := strings.Cut(strings.ToLower(key), ":"); !slices.Contains(lf.Options, optName) {
+				return nil, fmt.Errorf("task[%d] %q: unknown option %q of format %q", i, t.Name, key, t.Format)
+			}
+		}
 	}
 	return tasks, nil
 }
 
-func (gs *GeoSites) assembleDat(task DatTask) error {
-	datFileName := strings.ToLower(filepath.Base(task.Name))
-	geoSiteList := new(router.GeoSiteList)
+// geoSiteListEntryNum is the field number of the repeated entry field of
+// GeoSiteList, whose wire format is the plain concatenation of its records.
+var geoSiteListEntryNum = (&router.GeoSiteList{}).ProtoReflect().Descriptor().Fields().ByName("entry").Number()
 
+// newGeoSites sorts the sites and encodes each of them once, so that dat files
+// are assembled from the same records without marshaling them again.
+func newGeoSites(sites []*router.GeoSite) (*GeoSites, error) {
+	// Sort proto sites so the generated file is reproducible
+	slices.SortFunc(sites, func(a, b *router.GeoSite) int {
+		return strings.Compare(a.CountryCode, b.CountryCode)
+	})
+	gs := &GeoSites{
+		Sites:   sites,
+		SiteIdx: make(map[string]int, len(sites)),
+		Records: make([][]byte, len(sites)),
+	}
+	for i, site := range sites {
+		gs.SiteIdx[site.CountryCode] = i
+		siteBytes, err := proto.Marshal(site)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal site %q: %w", site.CountryCode, err)
+		}
+		record := make([]byte, 0, protowire.SizeTag(geoSiteListEntryNum)+protowire.SizeBytes(len(siteBytes)))
+		record = protowire.AppendTag(record, geoSiteListEntryNum, protowire.BytesType)
+		gs.Records[i] = protowire.AppendBytes(record, siteBytes)
+	}
+	return gs, nil
+}
+
+// selectSites returns the sorted indexes of the sites selected by the task.
+func (gs *GeoSites) selectSites(task DatTask) ([]int, error) {
+	var idxes []int
 	switch task.Mode {
 	case ModeAll:
-		geoSiteList.Entry = gs.Sites
+		idxes = make([]int, len(gs.Sites))
+		for i := range idxes {
+			idxes[i] = i
+		}
 	case ModeAllowlist:
-		allowedIdxes := make([]int, 0, len(task.Lists))
+		idxes = make([]int, 0, len(task.Lists))
 		for _, list := range task.Lists {
 			if idx, ok := gs.SiteIdx[strings.ToUpper(list)]; ok {
-				allowedIdxes = append(allowedIdxes, idx)
+				idxes = append(idxes, idx)
 			} else {
-				return fmt.Errorf("list %q not found for allowlist task", list)
+				return nil, fmt.Errorf("list %q not found for allowlist task", list)
 			}
 		}
-		slices.Sort(allowedIdxes)
-		allowedIdxes = slices.Compact(allowedIdxes) // Avoid duplicated lists
-		allowedlen := len(allowedIdxes)
-		if allowedlen == 0 {
-			return fmt.Errorf("allowlist needs at least one valid list")
-		}
-		geoSiteList.Entry = make([]*router.GeoSite, allowedlen)
-		for i, idx := range allowedIdxes {
-			geoSiteList.Entry[i] = gs.Sites[idx]
+		slices.Sort(idxes)
+		idxes = slices.Compact(idxes) // Avoid duplicated lists
+		if len(idxes) == 0 {
+			return nil, fmt.Errorf("allowlist needs at least one valid list")
 		}
 	case ModeDenylist:
 		deniedMap := make(map[int]bool, len(task.Lists))
@@ -157,45 +243,78 @@ func (gs *GeoSites) assembleDat(task DatTask) error {
 			if idx, ok := gs.SiteIdx[strings.ToUpper(list)]; ok {
 				deniedMap[idx] = true
 			} else {
-				fmt.Printf("[Warn] list %q not found in denylist task %q\n", list, task.Name)
+				warner.warnf(WarnDenylistMissing, "list %q not found in denylist task %q", list, task.Name)
 			}
 		}
-		deniedlen := len(deniedMap)
-		if deniedlen == 0 {
-			fmt.Printf("[Warn] nothing to deny in task %q\n", task.Name)
-			geoSiteList.Entry = gs.Sites
-		} else {
-			geoSiteList.Entry = make([]*router.GeoSite, 0, len(gs.Sites)-deniedlen)
-			for i, site := range gs.Sites {
-				if !deniedMap[i] {
-					geoSiteList.Entry = append(geoSiteList.Entry, site)
-				}
+		if len(deniedMap) == 0 {
+			warner.warnf(WarnDenylistEmpty, "nothing to deny in task %q", task.Name)
+		}
+		idxes = make([]int, 0, len(gs.Sites)-len(deniedMap))
+		for i := range gs.Sites {
+			if !deniedMap[i] {
+				idxes = append(idxes, i)
 			}
 		}
 	}
+	return idxes, nil
+}
+
+// countRules returns the number of rules of the indexed sites by type.
+func (gs *GeoSites) countRules(idxes []int) map[string]int {
+	sites := make([]*router.GeoSite, len(idxes))
+	for i, idx := range idxes {
+		sites[i] = gs.Sites[idx]
+	}
+	return manifest.CountRules(sites)
+}
 
-	protoBytes, err := proto.Marshal(geoSiteList)
+func (gs *GeoSites) assembleDat(task DatTask) error {
+	datFileName := strings.ToLower(filepath.Base(task.Name))
+	idxes, err := gs.selectSites(task)
 	if err != nil {
-		return fmt.Errorf("failed to marshal: %w", err)
+		return err
 	}
-	if err := os.WriteFile(filepath.Join(*outputDir, datFileName), protoBytes, 0644); err != nil {
+
+	// Stream the records instead of marshaling a GeoSiteList of all the sites
+	if err := writeOutput(datFileName, func(w io.Writer) error {
+		for _, idx := range idxes {
+			if _, err := w.Write(gs.Records[idx]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
 		return fmt.Errorf("failed to write file %q: %w", datFileName, err)
 	}
+	if err := addOutput(datFileName, FormatDat, len(idxes), gs.countRules(idxes)); err != nil {
+		return err
+	}
 	fmt.Printf("dat %q has been generated successfully\n", datFileName)
 	return nil
 }
 
-func writePlainList(listname string, entries []*Entry) error {
-	file, err := os.Create(filepath.Join(*outputDir, strings.ToLower(listname)+".txt"))
+// assembleGeositeDB writes the sites selected by the task as a sing-box
+// geosite.db file.
+func (gs *GeoSites) assembleGeositeDB(task DatTask) error {
+	dbFileName := strings.ToLower(filepath.Base(task.Name))
+	idxes, err := gs.selectSites(task)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
-	w := bufio.NewWriter(file)
-	for _, entry := range entries {
-		fmt.Fprintln(w, entry.Plain)
+	sites := make([]*router.GeoSite, len(idxes))
+	for i, idx := range idxes {
+		sites[i] = gs.Sites[idx]
+	}
+	if err := writeOutput(dbFileName, func(w io.Writer) error {
+		return geositedb.Write(w, geositedb.FromSites(sites))
+	}); err != nil {
+		return fmt.Errorf("failed to write file %q: %w", dbFileName, err)
+	}
+	if err := addOutput(dbFileName, FormatGeositeDB, len(idxes), manifest.CountRules(sites)); err != nil {
+		return err
 	}
-	return w.Flush()
+	fmt.Printf("geosite.db %q has been generated successfully\n", dbFileName)
+	return nil
 }
 
 func parseEntry(typ, rule string) (*Entry, []string, error) {
@@ -378,6 +497,7 @@ func (p *Processor) loadData(listName string, path string) error {
 	defer file.Close()
 
 	pl := p.getOrCreateParsedList(listName)
+	pl.HasFile = true
 	scanner := bufio.NewScanner(file)
 	lineIdx := 0
 	for scanner.Scan() {
@@ -404,9 +524,13 @@ func (p *Processor) loadData(listName string, path string) error {
 			if err != nil {
 				return fmt.Errorf("error in %q at line %d: %w", path, lineIdx, err)
 			}
+			entry.File, entry.Line = filepath.Base(path), lineIdx
 			for _, aff := range affs {
 				apl := p.getOrCreateParsedList(aff)
 				apl.Entries = append(apl.Entries, entry)
+				if !slices.Contains(apl.Affiliators, listName) {
+					apl.Affiliators = append(apl.Affiliators, listName)
+				}
 			}
 			pl.Entries = append(pl.Entries, entry)
 		}
@@ -436,52 +560,21 @@ func isMatchAttrFilters(entry *Entry, incFilter *Inclusion) bool {
 // A subdomain with attr(s) can only be trimed by parent domain with same attr(s)
 func polishList(roughMap map[string]*Entry) []*Entry {
 	finalList := make([]*Entry, 0, len(roughMap))
-	queuingList := make([]*Entry, 0, len(roughMap))
-	parentsMap := make(map[string]bool)
 	for _, entry := range roughMap {
 		switch entry.Type { // Bypass regexp and keyword
 		case dlc.RuleTypeRegexp, dlc.RuleTypeKeyword:
 			finalList = append(finalList, entry)
-		case dlc.RuleTypeDomain:
-			parentsMap[entry.Value] = true
-			if len(entry.Attrs) != 0 {
-				// `sub.example.org:@attr1,@attr2`
-				// Ensure no dot exists except the domain (entry.Value) part
-				_, domainAndAttrs, _ := strings.Cut(entry.Plain, ":")
-				parentsMap[domainAndAttrs] = true
-			}
-			queuingList = append(queuingList, entry)
-		case dlc.RuleTypeFullDomain:
-			queuingList = append(queuingList, entry)
 		}
 	}
-
-	for _, qentry := range queuingList {
-		isRedundant := false
-		var pd string // To be parent domain (with attrs)
-		if len(qentry.Attrs) == 0 {
-			pd = qentry.Value
-		} else {
-			_, pd, _ = strings.Cut(qentry.Plain, ":")
-		}
-		if qentry.Type == dlc.RuleTypeFullDomain {
-			pd = "." + pd // So that `domain:example.org` overrides `full:example.org`
-		}
-		for {
-			var hasParent bool
-			_, pd, hasParent = strings.Cut(pd, ".") // Go for next parent
-			if !hasParent {
-				break
-			}
-			if parentsMap[pd] {
-				isRedundant = true
-				break
-			}
-		}
-		if !isRedundant {
-			finalList = append(finalList, qentry)
+	parents := newDomainIndex(len(roughMap) - len(finalList))
+	for _, entry := range roughMap {
+		switch entry.Type {
+		case dlc.RuleTypeDomain, dlc.RuleTypeFullDomain:
+			parents.insert(entry)
 		}
 	}
+	parents.sort()
+	finalList = parents.appendNonRedundant(finalList)
 	// Sort final entries
 	slices.SortFunc(finalList, func(a, b *Entry) int {
 		return strings.Compare(a.Plain, b.Plain)
@@ -525,7 +618,7 @@ func (p *Processor) resolveList(plname string) (*ParsedList, error) {
 	}
 	pl.RoughEntries = roughEntries
 	if len(roughEntries) == 0 {
-		fmt.Printf("[Warn] ignore empty list %q\n", plname)
+		warner.warnf(WarnEmptyList, "ignore empty list %q", plname)
 	} else {
 		pl.FinalEntries = polishList(roughEntries)
 	}
@@ -533,12 +626,9 @@ func (p *Processor) resolveList(plname string) (*ParsedList, error) {
 	return pl, nil
 }
 
-func run() error {
-	fmt.Printf("using domain lists data in %q\n", *dataPath)
-
-	// Parse all lists in the data directory
-	processor := &Processor{parsedListByName: make(map[string]*ParsedList)}
-	err := filepath.WalkDir(*dataPath, func(path string, d os.DirEntry, err error) error {
+// loadDataDir parses all lists in the data directory.
+func (p *Processor) loadDataDir(dataPath string) error {
+	return filepath.WalkDir(dataPath, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
@@ -549,15 +639,57 @@ func run() error {
 		if !validateSiteName(listName) {
 			return fmt.Errorf("invalid list name: %q", listName)
 		}
-		return processor.loadData(listName, path)
+		return p.loadData(listName, path)
 	})
+}
+
+// resolveAll resolves the inclusions of all lists.
+func (p *Processor) resolveAll() error {
+	for plname := range p.parsedListByName {
+		if _, err := p.resolveList(plname); err != nil {
+			return fmt.Errorf("failed to resolveList %q: %w", plname, err)
+		}
+	}
+	return nil
+}
+
+func run() error {
+	var err error
+	if warner, err = newWarner(*strictMode, *warnLevels); err != nil {
+		return fmt.Errorf("failed to parse warnings option: %w", err)
+	}
+	formats, err := parseExportFormats(*exportFormats)
+	if err != nil {
+		return fmt.Errorf("failed to parse export formats: %w", err)
+	}
+	exportOpts, err := parseExportOptions(*exportOptions, formats)
 	if err != nil {
+		return fmt.Errorf("failed to parse export options: %w", err)
+	}
+	disjoint, err := parseDisjointPairs(*disjointPairs)
+	if err != nil {
+		return fmt.Errorf("failed to parse disjoint pairs: %w", err)
+	}
+	built, err := buildTime()
+	if err != nil {
+		return err
+	}
+	if artifacts, err = artifact.ParseOptions(*compress, *checksums, built); err != nil {
+		return fmt.Errorf("failed to parse artifact options: %w", err)
+	}
+	fmt.Printf("using domain lists data in %q\n", *dataPath)
+
+	processor := &Processor{parsedListByName: make(map[string]*ParsedList)}
+	if err := processor.loadDataDir(*dataPath); err != nil {
 		return fmt.Errorf("failed to loadData: %w", err)
 	}
-	// Resolve the inclusions of all lists
-	for plname := range processor.parsedListByName {
-		if _, err := processor.resolveList(plname); err != nil {
-			return fmt.Errorf("failed to resolveList %q: %w", plname, err)
+	if err := processor.resolveAll(); err != nil {
+		return err
+	}
+
+	if *manifestName != "" {
+		if outputs, err = loadManifest(*dataPath, *datProfile); err != nil {
+			return fmt.Errorf("failed to create manifest: %w", err)
 		}
 	}
 
@@ -565,64 +697,158 @@ func run() error {
 	if err := os.MkdirAll(*outputDir, 0755); err != nil {
 		return fmt.Errorf("failed to create output directory: %w", err)
 	}
-	// Export plaintext lists
+	// Export lists in all the formats
 	failedCount := 0
+	exported := make(map[string]bool)
 	for rawEpList := range strings.SplitSeq(*exportLists, ",") {
 		if epList := strings.TrimSpace(rawEpList); epList != "" {
-			pl, exist := processor.parsedListByName[strings.ToUpper(epList)]
-			if !exist || len(pl.FinalEntries) == 0 {
-				fmt.Printf("[Warn] list %q does not exist or is empty\n", epList)
+			el, err := processor.selectList(epList)
+			if err != nil {
+				return fmt.Errorf("failed to select list %q: %w", epList, err)
+			}
+			if el == nil || len(el.Entries) == 0 {
+				warner.warnf(WarnExportMissing, "list %q does not exist or is empty", epList)
 				continue
 			}
-			if err := writePlainList(epList, pl.FinalEntries); err != nil {
-				fmt.Printf("[Error] failed to write list %q: %v\n", epList, err)
-				failedCount++
+			if exported[el.Name] { // The same selection in another order
 				continue
 			}
-			fmt.Printf("list %q has been generated successfully\n", epList)
+			exported[el.Name] = true
+			for _, format := range formats {
+				lf := listFormats[format]
+				filename := el.Name + lf.Ext
+				if err := writeOutput(filename, func(w io.Writer) error {
+					return processor.writeLists(w, lf, []*ExportList{el}, exportOpts)
+				}); err != nil {
+					fmt.Printf("[Error] failed to export list %q as %s: %v\n", epList, format, err)
+					failedCount++
+					continue
+				}
+				if err := addOutput(filename, format, 1, countListRules([]*ExportList{el})); err != nil {
+					fmt.Printf("[Error] failed to add %q to manifest: %v\n", filename, err)
+					failedCount++
+					continue
+				}
+				fmt.Printf("list %q has been exported to %q successfully\n", epList, filename)
+			}
 		}
 	}
 
-	// Generate proto sites
-	listsCount := len(processor.parsedListByName)
-	gs := &GeoSites{
-		Sites:   make([]*router.GeoSite, 0, listsCount),
-		SiteIdx: make(map[string]int, listsCount),
+	// Report the classes of all lists
+	if *listReport != "" {
+		infos := processor.classifyLists()
+		if err := writeOutput(*listReport, func(w io.Writer) error {
+			return writeListReport(w, infos)
+		}); err != nil {
+			fmt.Printf("[Error] failed to write list report %q: %v\n", *listReport, err)
+			failedCount++
+		} else if err := addOutput(*listReport, "listreport", len(infos), nil); err != nil {
+			fmt.Printf("[Error] failed to add list report %q to manifest: %v\n", *listReport, err)
+			failedCount++
+		} else {
+			fmt.Printf("list report %q has been generated successfully\n", *listReport)
+		}
 	}
+
+	// Write the SQLite database of all lists
+	if *sqliteDB != "" {
+		if err := processor.writeSQLite(filepath.Join(*outputDir, *sqliteDB)); err != nil {
+			fmt.Printf("[Error] failed to write SQLite database %q: %v\n", *sqliteDB, err)
+			failedCount++
+		} else if err := finishOutput(*sqliteDB, "sqlite"); err != nil {
+			fmt.Printf("[Error] failed to finish SQLite database %q: %v\n", *sqliteDB, err)
+			failedCount++
+		} else {
+			fmt.Printf("SQLite database %q has been generated successfully\n", *sqliteDB)
+		}
+	}
+
+	// Report the overlaps between lists
+	violations := 0
+	if *overlapLists != "" || len(disjoint) != 0 {
+		var names []string
+		for rawList := range strings.SplitSeq(*overlapLists, ",") {
+			if list := strings.ToUpper(strings.TrimSpace(rawList)); list != "" && !slices.Contains(names, list) {
+				names = append(names, list)
+			}
+		}
+		if violations, err = processor.reportOverlaps(names, disjoint); err != nil {
+			return fmt.Errorf("failed to reportOverlaps: %w", err)
+		}
+	}
+
+	// Generate proto sites
+	sites := make([]*router.GeoSite, 0, len(processor.parsedListByName))
 	for siteName, pl := range processor.parsedListByName {
 		if len(pl.FinalEntries) == 0 { // Skip empty lists
 			continue
 		}
-		gs.Sites = append(gs.Sites, makeProtoList(siteName, pl.FinalEntries))
+		sites = append(sites, makeProtoList(siteName, pl.FinalEntries))
 	}
-	// Sort proto sites so the generated file is reproducible
-	slices.SortFunc(gs.Sites, func(a, b *router.GeoSite) int {
-		return strings.Compare(a.CountryCode, b.CountryCode)
-	})
-	for i := range gs.Sites {
-		gs.SiteIdx[gs.Sites[i].CountryCode] = i
+	gs, err := newGeoSites(sites)
+	if err != nil {
+		return fmt.Errorf("failed to generate proto sites: %w", err)
 	}
 
 	// Load tasks and generate dat files
 	var tasks []DatTask
 	if *datProfile == "" {
 		tasks = []DatTask{{Name: *outputName, Mode: ModeAll}}
+		if *singboxDB != "" {
+			tasks = append(tasks, DatTask{Name: *singboxDB, Mode: ModeAll, Format: FormatGeositeDB})
+		}
+		if *splitDir != "" {
+			tasks = append(tasks, DatTask{Name: *splitDir, Mode: ModeAll, Format: FormatSplit})
+		}
 	} else {
-		var err error
 		tasks, err = loadTasks(*datProfile)
 		if err != nil {
 			return fmt.Errorf("failed to loadTasks %q: %v", *datProfile, err)
 		}
 	}
 	for _, task := range tasks {
-		if err := gs.assembleDat(task); err != nil {
-			fmt.Printf("[Error] failed to assembleDat %q: %v\n", task.Name, err)
+		switch task.Format {
+		case "", FormatDat:
+			if err := gs.assembleDat(task); err != nil {
+				fmt.Printf("[Error] failed to assembleDat %q: %v\n", task.Name, err)
+				failedCount++
+			}
+		case FormatGeositeDB:
+			if err := gs.assembleGeositeDB(task); err != nil {
+				fmt.Printf("[Error] failed to assembleGeositeDB %q: %v\n", task.Name, err)
+				failedCount++
+			}
+		case FormatSplit:
+			if err := gs.assembleSplit(task); err != nil {
+				fmt.Printf("[Error] failed to assembleSplit %q: %v\n", task.Name, err)
+				failedCount++
+			}
+		default:
+			if err := processor.exportTask(task); err != nil {
+				fmt.Printf("[Error] failed to exportTask %q: %v\n", task.Name, err)
+				failedCount++
+			}
+		}
+	}
+
+	// Describe all the generated files
+	if outputs != nil {
+		if err := outputs.Write(filepath.Join(*outputDir, *manifestName)); err != nil {
+			fmt.Printf("[Error] failed to write manifest %q: %v\n", *manifestName, err)
 			failedCount++
+		} else {
+			fmt.Printf("manifest %q has been generated successfully\n", *manifestName)
 		}
 	}
 	if failedCount > 0 {
 		return fmt.Errorf("%d output file(s) failed to be generated", failedCount)
 	}
+	if violations > 0 {
+		return fmt.Errorf("%d pair(s) of lists expected to be disjoint overlap", violations)
+	}
+	if warner.ErrorCount > 0 {
+		return fmt.Errorf("%d warning(s) treated as error(s)", warner.ErrorCount)
+	}
 	return nil
 }
 
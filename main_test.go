@@ -1,11 +1,15 @@
 package main
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
 	"slices"
 	"strings"
 	"testing"
+
+	router "github.com/v2fly/v2ray-core/v5/app/router/routercommon"
+	"google.golang.org/protobuf/proto"
 )
 
 func TestParseEntry(t *testing.T) {
@@ -193,6 +197,68 @@ func TestResolveCircularInclusion(t *testing.T) {
 	}
 }
 
+// TestAssembleDat makes sure that dat files assembled from the records of sites
+// are byte-identical to marshaling a GeoSiteList of the selected sites.
+func TestAssembleDat(t *testing.T) {
+	processor := loadTestData(t)
+	sites := make([]*router.GeoSite, 0, len(processor.parsedListByName))
+	for siteName, pl := range processor.parsedListByName {
+		if len(pl.FinalEntries) != 0 {
+			sites = append(sites, makeProtoList(siteName, pl.FinalEntries))
+		}
+	}
+	gs, err := newGeoSites(sites)
+	if err != nil {
+		t.Fatalf("newGeoSites got unexpected error: %v", err)
+	}
+
+	defer func(dir string) { *outputDir = dir }(*outputDir)
+	*outputDir = t.TempDir()
+	tasks := []DatTask{
+		{Name: "all.dat", Mode: ModeAll},
+		{Name: "allow.dat", Mode: ModeAllowlist, Lists: []string{"google", "cn", "Google"}},
+		{Name: "deny.dat", Mode: ModeDenylist, Lists: []string{"cn", "not-exist"}},
+		{Name: "nothing-denied.dat", Mode: ModeDenylist},
+	}
+	for _, task := range tasks {
+		if err := gs.assembleDat(task); err != nil {
+			t.Fatalf("assembleDat(%q) got unexpected error: %v", task.Name, err)
+		}
+		idxes, err := gs.selectSites(task)
+		if err != nil {
+			t.Fatalf("selectSites(%q) got unexpected error: %v", task.Name, err)
+		}
+		geoSiteList := &router.GeoSiteList{Entry: make([]*router.GeoSite, 0, len(idxes))}
+		for _, idx := range idxes {
+			geoSiteList.Entry = append(geoSiteList.Entry, gs.Sites[idx])
+		}
+		want, err := proto.Marshal(geoSiteList)
+		if err != nil {
+			t.Fatalf("proto.Marshal(%q) got unexpected error: %v", task.Name, err)
+		}
+		got, err := os.ReadFile(filepath.Join(*outputDir, task.Name))
+		if err != nil {
+			t.Fatalf("failed to read %q: %v", task.Name, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("assembleDat(%q) differs from proto.Marshal: got %d bytes, want %d", task.Name, len(got), len(want))
+		}
+	}
+}
+
+// loadTestData loads and resolves all lists in the data directory.
+func loadTestData(tb testing.TB) *Processor {
+	tb.Helper()
+	processor := &Processor{parsedListByName: make(map[string]*ParsedList)}
+	if err := processor.loadDataDir("./data"); err != nil {
+		tb.Fatalf("loadDataDir got unexpected error: %v", err)
+	}
+	if err := processor.resolveAll(); err != nil {
+		tb.Fatalf("resolveAll got unexpected error: %v", err)
+	}
+	return processor
+}
+
 func assertList(t *testing.T, p *Processor, name string, want []string) {
 	t.Helper()
 	pl, exist := p.parsedListByName[name]
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"runtime/debug"
+	"time"
+
+	"github.com/v2fly/domain-list-community/internal/manifest"
+)
+
+var manifestName = flag.String("manifest", "", "Name of the manifest file describing the generated files, e.g. 'manifest.json' (empty to skip)")
+
+// outputs collects the generated files into the manifest, or nil if it is not
+// written.
+var outputs *manifest.Manifest
+
+// generatorVersion returns the version of the generator module, with the VCS
+// revision if it is stamped into the binary.
+func generatorVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	version := info.Main.Version
+	var revision, modified string
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value
+		}
+	}
+	if revision != "" {
+		version += "+" + revision
+		if modified == "true" {
+			version += "-dirty"
+		}
+	}
+	return version
+}
+
+// hashDataDir returns the hex SHA-256 digest of the data directory, which is
+// the digest of the lines like `sha256sum` of all its files by their paths
+// relative to the directory in byte order, e.g. `<hex>  cn`.
+func hashDataDir(dataPath string) (string, error) {
+	h := sha256.New()
+	err := filepath.WalkDir(dataPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dataPath, path)
+		if err != nil {
+			return err
+		}
+		_, sum, err := manifest.HashFile(path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s  %s\n", sum, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// loadManifest returns the manifest of the build from the data directory and
+// the dat profile, if any. The files of the manifest in the output directory
+// are kept if it describes the same data and profile, so that the files of
+// several runs, like the ones of datdump, are described together.
+func loadManifest(dataPath, profilePath string) (*manifest.Manifest, error) {
+	built, err := buildTime()
+	if err != nil {
+		return nil, err
+	}
+	data := &manifest.Source{Path: dataPath}
+	if data.SHA256, err = hashDataDir(dataPath); err != nil {
+		return nil, fmt.Errorf("failed to hash data directory: %w", err)
+	}
+	var profile *manifest.Source
+	if profilePath != "" {
+		profile = &manifest.Source{Path: profilePath}
+		if _, profile.SHA256, err = manifest.HashFile(profilePath); err != nil {
+			return nil, fmt.Errorf("failed to hash dat profile: %w", err)
+		}
+	}
+
+	m, err := manifest.Read(filepath.Join(*outputDir, *manifestName))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if err != nil || m.Data == nil || m.Data.SHA256 != data.SHA256 || sourceSHA256(m.Profile) != sourceSHA256(profile) {
+		m = &manifest.Manifest{Files: []*manifest.File{}}
+	}
+	m.Generator = generatorHomepage
+	m.Version = generatorVersion()
+	m.Built = built.Format(time.RFC3339)
+	m.Data, m.Profile = data, profile
+	return m, nil
+}
+
+// sourceSHA256 returns the digest of the source, or "" if it is nil.
+func sourceSHA256(source *manifest.Source) string {
+	if source == nil {
+		return ""
+	}
+	return source.SHA256
+}
+
+// addOutput adds the generated file in the output directory, and its
+// compressed variants and checksum sidecars, to the manifest if it is written.
+func addOutput(filename, kind string, lists int, rules map[string]int) error {
+	return outputs.AddFile(*outputDir, filename, kind, lists, rules, artifacts)
+}
+
+// finishOutput writes the compressed variants and checksum sidecars of the
+// file in the output directory which is not written by writeOutput, and adds
+// them to the manifest.
+func finishOutput(filename, kind string) error {
+	if artifacts != nil {
+		if _, err := artifacts.Write(filepath.Join(*outputDir, filename)); err != nil {
+			return err
+		}
+	}
+	return addOutput(filename, kind, 0, nil)
+}
+
+// countListRules returns the number of rules of the lists by type.
+func countListRules(lists []*ExportList) map[string]int {
+	counts := make(map[string]int)
+	for _, list := range lists {
+		for _, entry := range list.Entries {
+			counts[entry.Type]++
+		}
+	}
+	return counts
+}
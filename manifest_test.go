@@ -0,0 +1,132 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/v2fly/domain-list-community/internal/artifact"
+	"github.com/v2fly/domain-list-community/internal/manifest"
+)
+
+func TestHashDataDir(t *testing.T) {
+	dataPath := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dataPath, "sub"), 0755); err != nil {
+		t.Fatalf("failed to create test directory: %v", err)
+	}
+	files := map[string]string{"cn": "domain:example.cn\n", "sub/google": "domain:example.com\n"}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dataPath, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write test data %q: %v", name, err)
+		}
+	}
+	got, err := hashDataDir(dataPath)
+	if err != nil {
+		t.Fatalf("hashDataDir got unexpected error: %v", err)
+	}
+	sum := func(s string) string {
+		h := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(h[:])
+	}
+	// The same as `sha256sum cn sub/google | sha256sum`
+	want := sum(sum(files["cn"]) + "  cn\n" + sum(files["sub/google"]) + "  sub/google\n")
+	if got != want {
+		t.Errorf("hashDataDir() = %s, want %s", got, want)
+	}
+}
+
+func TestManifest(t *testing.T) {
+	defer func(dir string) { *outputDir = dir }(*outputDir)
+	*outputDir = t.TempDir()
+	defer func(name string) { *manifestName = name }(*manifestName)
+	*manifestName = "manifest.json"
+	defer func(opts *artifact.Options) { artifacts = opts }(artifacts)
+	defer func(m *manifest.Manifest) { outputs = m }(outputs)
+	var err error
+	if artifacts, err = artifact.ParseOptions("gz", "sha256", time.Unix(0, 0)); err != nil {
+		t.Fatalf("ParseOptions got unexpected error: %v", err)
+	}
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+
+	dataPath := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dataPath, "cn"), []byte("domain:example.cn\n"), 0644); err != nil {
+		t.Fatalf("failed to write test data: %v", err)
+	}
+	profile := filepath.Join(dataPath, "profile.json")
+	if err := os.WriteFile(profile, []byte("[]"), 0644); err != nil {
+		t.Fatalf("failed to write test profile: %v", err)
+	}
+	if outputs, err = loadManifest(dataPath, profile); err != nil {
+		t.Fatalf("loadManifest got unexpected error: %v", err)
+	}
+	if outputs.Built != "2023-11-14T22:13:20Z" || outputs.Data.SHA256 == "" || outputs.Profile == nil || outputs.Profile.SHA256 == "" || len(outputs.Files) != 0 {
+		t.Errorf("loadManifest() = %+v, want build time, data and profile hashes and no file", outputs)
+	}
+
+	for _, content := range []string{"stale", "fresh"} { // Generated again
+		if err := writeOutput("cn.txt", func(w io.Writer) error {
+			_, err := w.Write([]byte(content))
+			return err
+		}); err != nil {
+			t.Fatalf("writeOutput got unexpected error: %v", err)
+		}
+		if err := addOutput("cn.txt", "txt", 1, map[string]int{"domain": 1}); err != nil {
+			t.Fatalf("addOutput got unexpected error: %v", err)
+		}
+	}
+	path := filepath.Join(*outputDir, *manifestName)
+	if err := outputs.Write(path); err != nil {
+		t.Fatalf("Write got unexpected error: %v", err)
+	}
+
+	got, err := manifest.Read(path)
+	if err != nil {
+		t.Fatalf("Read got unexpected error: %v", err)
+	}
+	want := []struct{ name, kind, source string }{
+		{"cn.txt", "txt", ""},
+		{"cn.txt.gz", manifest.KindCompressed, "cn.txt"},
+		{"cn.txt.gz.sha256sum", manifest.KindChecksum, "cn.txt.gz"},
+		{"cn.txt.sha256sum", manifest.KindChecksum, "cn.txt"},
+	}
+	if len(got.Files) != len(want) {
+		t.Fatalf("manifest has %d files, want %d: %+v", len(got.Files), len(want), got.Files)
+	}
+	for i, w := range want {
+		file := got.Files[i]
+		if file.Name != w.name || file.Kind != w.kind || file.Source != w.source {
+			t.Errorf("manifest file[%d] = %s/%s/%s, want %s/%s/%s", i, file.Name, file.Kind, file.Source, w.name, w.kind, w.source)
+		}
+		size, sum, err := manifest.HashFile(filepath.Join(*outputDir, file.Name))
+		if err != nil || file.Size != size || file.SHA256 != sum {
+			t.Errorf("manifest file %q = %d %s, want %d %s", file.Name, file.Size, file.SHA256, size, sum)
+		}
+	}
+	if got.Files[0].Lists != 1 || got.Files[0].Rules["domain"] != 1 {
+		t.Errorf("manifest file %q has %d lists and rules %v, want 1 list and 1 domain rule", got.Files[0].Name, got.Files[0].Lists, got.Files[0].Rules)
+	}
+
+	// The manifest lists every file in the output directory but itself
+	entries, err := os.ReadDir(*outputDir)
+	if err != nil {
+		t.Fatalf("failed to read output directory: %v", err)
+	}
+	for _, entry := range entries {
+		if name := entry.Name(); name != *manifestName && !slices.ContainsFunc(got.Files, func(f *manifest.File) bool { return f.Name == name }) {
+			t.Errorf("output file %q is not listed in manifest", name)
+		}
+	}
+
+	// Another run of the same data keeps the files, and of other data does not
+	if outputs, err = loadManifest(dataPath, profile); err != nil || len(outputs.Files) != len(want) {
+		t.Errorf("loadManifest() of the same data = %v, %v, want %d files", outputs, err, len(want))
+	}
+	if outputs, err = loadManifest(dataPath, ""); err != nil || len(outputs.Files) != 0 || outputs.Profile != nil {
+		t.Errorf("loadManifest() without profile = %v, %v, want no file and no profile", outputs, err)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/v2fly/domain-list-community/internal/dlc"
+)
+
+// Behaviors of mihomo rule-providers
+const (
+	MihomoBehaviorAuto      string = "auto" // Domain if possible, otherwise classical
+	MihomoBehaviorDomain    string = "domain"
+	MihomoBehaviorClassical string = "classical"
+)
+
+const (
+	mrsMagic          = "MRS\x01" // MRS version 1
+	mrsBehaviorDomain = 0
+)
+
+// mihomoPayload returns the behavior of the rule-provider of the lists and its
+// payload, and reports the rules which the behavior is unable to express.
+func mihomoPayload(format string, lists []*ExportList, opts ExportOptions) (string, []string, error) {
+	entries := stripAttrs(mergeEntries(lists))
+	behavior := opts.get("behavior", MihomoBehaviorAuto)
+	switch behavior {
+	case MihomoBehaviorAuto:
+		behavior = MihomoBehaviorDomain
+		if slices.ContainsFunc(entries, func(entry *Entry) bool {
+			return entry.Type != dlc.RuleTypeDomain && entry.Type != dlc.RuleTypeFullDomain
+		}) {
+			behavior = MihomoBehaviorClassical
+		}
+	case MihomoBehaviorDomain, MihomoBehaviorClassical:
+	default:
+		return "", nil, fmt.Errorf("invalid mihomo behavior: %q", behavior)
+	}
+
+	payload := make([]string, 0, len(entries))
+	var dropped []*Entry
+	for _, entry := range entries {
+		if behavior == MihomoBehaviorDomain {
+			switch entry.Type {
+			case dlc.RuleTypeDomain:
+				payload = append(payload, "+."+entry.Value)
+			case dlc.RuleTypeFullDomain:
+				payload = append(payload, entry.Value)
+			default:
+				dropped = append(dropped, entry)
+			}
+			continue
+		}
+		switch entry.Type {
+		case dlc.RuleTypeDomain:
+			payload = append(payload, "DOMAIN-SUFFIX,"+entry.Value)
+		case dlc.RuleTypeFullDomain:
+			payload = append(payload, "DOMAIN,"+entry.Value)
+		case dlc.RuleTypeKeyword:
+			payload = append(payload, "DOMAIN-KEYWORD,"+entry.Value)
+		case dlc.RuleTypeRegexp:
+			// Commas separate the fields of classical rules
+			if strings.Contains(entry.Value, ",") {
+				dropped = append(dropped, entry)
+			} else {
+				payload = append(payload, "DOMAIN-REGEX,"+entry.Value)
+			}
+		}
+	}
+	warnDropped(format, lists, dropped)
+	return behavior, payload, nil
+}
+
+// writeMihomoYAML writes the lists as a mihomo rule-provider of yaml format,
+// with its behavior in the leading comment.
+func writeMihomoYAML(w io.Writer, lists []*ExportList, opts ExportOptions) error {
+	behavior, payload, err := mihomoPayload("mihomo", lists, opts)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "# behavior: %s\npayload:\n", behavior)
+	for _, rule := range payload {
+		fmt.Fprintf(w, "  - '%s'\n", strings.ReplaceAll(rule, "'", "''"))
+	}
+	return nil
+}
+
+// writeMihomoText writes the lists as a mihomo rule-provider of text format.
+func writeMihomoText(w io.Writer, lists []*ExportList, opts ExportOptions) error {
+	_, payload, err := mihomoPayload("mihomo-text", lists, opts)
+	if err != nil {
+		return err
+	}
+	for _, rule := range payload {
+		fmt.Fprintln(w, rule)
+	}
+	return nil
+}
+
+// writeMihomoBinary writes the lists as a mihomo rule-provider of mrs format,
+// which only supports the domain behavior.
+func writeMihomoBinary(w io.Writer, lists []*ExportList, _ ExportOptions) error {
+	_, payload, err := mihomoPayload("mrs", lists, ExportOptions{"behavior": MihomoBehaviorDomain})
+	if err != nil {
+		return err
+	}
+	if len(payload) == 0 {
+		return fmt.Errorf("no domain or full type rule for the domain behavior")
+	}
+	keys := make([]string, len(payload))
+	for i, rule := range payload {
+		keys[i] = reverseDomain(rule)
+	}
+	// `+.example.com` stands for the key of `example.com` and the key of its
+	// subdomains, where '+' matches the remaining labels
+	for _, rule := range payload {
+		if domain, ok := strings.CutPrefix(rule, "+."); ok {
+			keys = append(keys, reverseDomain(domain))
+		}
+	}
+	slices.Sort(keys)
+	keys = slices.Compact(keys)
+
+	b := append([]byte(mrsMagic), mrsBehaviorDomain)
+	b = binary.BigEndian.AppendUint64(b, uint64(len(payload))) // Number of rules
+	b = binary.BigEndian.AppendUint64(b, 0)                    // Length of extra data
+	b = newSuccinctSet(keys).appendMrs(b)
+	zw, err := zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.SpeedBestCompression))
+	if err != nil {
+		return err
+	}
+	if _, err := zw.Write(b); err != nil {
+		zw.Close()
+		return err
+	}
+	return zw.Close()
+}
+
+// appendMrs appends the set encoded as a mihomo domain set.
+func (ss *succinctSet) appendMrs(b []byte) []byte {
+	b = append(b, 1) // Version
+	b = binary.BigEndian.AppendUint64(b, uint64(len(ss.leaves)))
+	for _, word := range ss.leaves {
+		b = binary.BigEndian.AppendUint64(b, word)
+	}
+	b = binary.BigEndian.AppendUint64(b, uint64(len(ss.labelBitmap)))
+	for _, word := range ss.labelBitmap {
+		b = binary.BigEndian.AppendUint64(b, word)
+	}
+	b = binary.BigEndian.AppendUint64(b, uint64(len(ss.labels)))
+	return append(b, ss.labels...)
+}
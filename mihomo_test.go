@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"slices"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestWriteMihomo(t *testing.T) {
+	domainList := testExportList(t, "domain",
+		"domain:example.com @ads",
+		"full:www.example.org",
+	)
+	classicalList := testExportList(t, "classical",
+		"domain:example.com",
+		"full:www.example.org @cn",
+		"keyword:tracker",
+		`regexp:^ads\d+\.example\.edu$`,
+		"regexp:^x{1,3}\\.example\\.net$", // Dropped, as commas separate the fields
+		"regexp:^it's\\.example\\.net$",
+	)
+	testCases := []struct {
+		name  string
+		write func(io.Writer, []*ExportList, ExportOptions) error
+		list  *ExportList
+		opts  ExportOptions
+		want  string
+	}{
+		{"yaml domain", writeMihomoYAML, domainList, nil,
+			"# behavior: domain\npayload:\n  - '+.example.com'\n  - 'www.example.org'\n"},
+		{"yaml classical", writeMihomoYAML, classicalList, nil,
+			"# behavior: classical\npayload:\n" +
+				"  - 'DOMAIN-SUFFIX,example.com'\n" +
+				"  - 'DOMAIN,www.example.org'\n" +
+				"  - 'DOMAIN-KEYWORD,tracker'\n" +
+				"  - 'DOMAIN-REGEX,^ads\\d+\\.example\\.edu$'\n" +
+				"  - 'DOMAIN-REGEX,^it''s\\.example\\.net$'\n"},
+		{"yaml forced classical", writeMihomoYAML, domainList, ExportOptions{"behavior": MihomoBehaviorClassical},
+			"# behavior: classical\npayload:\n  - 'DOMAIN-SUFFIX,example.com'\n  - 'DOMAIN,www.example.org'\n"},
+		{"text forced domain", writeMihomoText, classicalList, ExportOptions{"behavior": MihomoBehaviorDomain},
+			"+.example.com\nwww.example.org\n"},
+	}
+	for _, tc := range testCases {
+		var buf bytes.Buffer
+		if err := tc.write(&buf, []*ExportList{tc.list}, tc.opts); err != nil {
+			t.Errorf("%s got unexpected error: %v", tc.name, err)
+			continue
+		}
+		if got := buf.String(); got != tc.want {
+			t.Errorf("%s = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+
+	if err := writeMihomoText(io.Discard, []*ExportList{domainList}, ExportOptions{"behavior": "ipcidr"}); err == nil {
+		t.Error("writeMihomoText() with behavior ipcidr = nil error, want invalid behavior error")
+	}
+}
+
+// TestWriteMihomoBinary decodes the mrs file back into the domain payload.
+func TestWriteMihomoBinary(t *testing.T) {
+	lists := []*ExportList{testExportList(t, "test",
+		"domain:example.com @ads",
+		"domain:example.net",
+		"full:www.example.org",
+		"full:example.net", // Redundant
+		"keyword:tracker",  // Dropped
+	)}
+	var buf bytes.Buffer
+	if err := writeMihomoBinary(&buf, lists, nil); err != nil {
+		t.Fatalf("writeMihomoBinary got unexpected error: %v", err)
+	}
+	count, keys, err := readMihomoBinary(&buf)
+	if err != nil {
+		t.Fatalf("readMihomoBinary got unexpected error: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("readMihomoBinary() count = %d, want 3", count)
+	}
+	var domains []string
+	for _, key := range keys {
+		domains = append(domains, reverseDomain(key))
+	}
+	slices.Sort(domains)
+	want := []string{"+.example.com", "+.example.net", "example.com", "example.net", "www.example.org"}
+	if !slices.Equal(domains, want) {
+		t.Errorf("readMihomoBinary() domains = %q, want %q", domains, want)
+	}
+
+	keywords := []*ExportList{testExportList(t, "keywords", "keyword:tracker")}
+	if err := writeMihomoBinary(io.Discard, keywords, nil); err == nil {
+		t.Error("writeMihomoBinary() of keywords only = nil error, want error")
+	}
+}
+
+// readMihomoBinary decodes the number of rules and the keys of the domain set
+// of a mrs file written by writeMihomoBinary.
+func readMihomoBinary(r io.Reader) (int64, []string, error) {
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer zr.Close()
+	b, err := io.ReadAll(zr)
+	if err != nil {
+		return 0, nil, err
+	}
+	header := len(mrsMagic) + 1 + 8 + 8
+	if len(b) < header || string(b[:len(mrsMagic)]) != mrsMagic || b[len(mrsMagic)] != mrsBehaviorDomain {
+		return 0, nil, fmt.Errorf("invalid header %q", b[:min(len(b), header)])
+	}
+	count := int64(binary.BigEndian.Uint64(b[len(mrsMagic)+1:]))
+	if extra := binary.BigEndian.Uint64(b[len(mrsMagic)+9:]); extra != 0 {
+		return 0, nil, fmt.Errorf("got %d bytes of extra data, want 0", extra)
+	}
+	r = bytes.NewReader(b[header:])
+	var version uint8
+	if err := binary.Read(r, binary.BigEndian, &version); err != nil || version != 1 {
+		return 0, nil, fmt.Errorf("got domain set version %d, want 1: %v", version, err)
+	}
+	readWords := func() ([]uint64, error) {
+		var n int64
+		if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+			return nil, err
+		}
+		words := make([]uint64, n)
+		return words, binary.Read(r, binary.BigEndian, words)
+	}
+	leaves, err := readWords()
+	if err != nil {
+		return 0, nil, err
+	}
+	labelBitmap, err := readWords()
+	if err != nil {
+		return 0, nil, err
+	}
+	var n int64
+	if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+		return 0, nil, err
+	}
+	labels := make([]byte, n)
+	if _, err := io.ReadFull(r, labels); err != nil {
+		return 0, nil, err
+	}
+	if rest, _ := io.ReadAll(r); len(rest) != 0 {
+		return 0, nil, fmt.Errorf("got %d bytes of trailing data", len(rest))
+	}
+	return count, succinctKeys(leaves, labelBitmap, labels), nil
+}
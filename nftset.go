@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	// nftIdentifier matches the identifiers of nftables.
+	nftIdentifier = regexp.MustCompile(`^[A-Za-z_][0-9A-Za-z_]*$`)
+	// nftTimeout matches the timeouts of nftables sets, like `1h30m`.
+	nftTimeout = regexp.MustCompile(`^([0-9]+[dhms])+$`)
+)
+
+// nftSetName returns the default name of the nftables define and sets of a
+// list, like `geosite_cn_no_ads` for `cn@-ads`.
+func nftSetName(list string) string {
+	name := strings.NewReplacer("@-", "_no_", "@", "_", "-", "_").Replace(list)
+	return "geosite_" + strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// writeNftSet writes the lists as nftables definitions to include in a table,
+// like the table of OpenWrt fw4. For each list, a define of its domains lists
+// one domain per line for the tools filling the IPv4 and IPv6 sets of the
+// list by DNS, and every domain also matches its subdomains. The names are
+// from the `name` option of each list, or nftSetName by default. The sets
+// have the `timeout` flag if the `timeout` option is set, and the `interval`
+// flag only if the `interval` option is true, which DNS filled sets of single
+// addresses do not need, and older nft versions reject with timeouts.
+func writeNftSet(w io.Writer, lists []*ExportList, opts ExportOptions) error {
+	for i, list := range lists {
+		name := opts.getFor("name", list.Name, nftSetName(list.Name))
+		if !nftIdentifier.MatchString(name) {
+			return fmt.Errorf("invalid name %q of list %q", name, list.Name)
+		}
+		timeout := opts.getFor("timeout", list.Name, "")
+		if timeout != "" && !nftTimeout.MatchString(timeout) {
+			return fmt.Errorf("invalid timeout %q of list %q", timeout, list.Name)
+		}
+		rawInterval := opts.getFor("interval", list.Name, "false")
+		interval, err := strconv.ParseBool(rawInterval)
+		if err != nil {
+			return fmt.Errorf("invalid interval %q of list %q", rawInterval, list.Name)
+		}
+		var flags []string
+		if interval {
+			flags = append(flags, "interval")
+		}
+		if timeout != "" {
+			flags = append(flags, "timeout")
+		}
+
+		if i != 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "# geosite:%s\n", list.Name)
+		domains := suffixDomains("nftset", []*ExportList{list}, false)
+		slices.Sort(domains) // Diff friendly regardless of the types of rules
+		if len(domains) != 0 {
+			fmt.Fprintf(w, "define %s = {\n", name)
+			for j, domain := range domains {
+				if j != len(domains)-1 {
+					fmt.Fprintf(w, "\t%q,\n", domain)
+				} else {
+					fmt.Fprintf(w, "\t%q\n", domain)
+				}
+			}
+			fmt.Fprint(w, "}\n\n")
+		}
+		for _, set := range []struct{ suffix, typ string }{{"_v4", "ipv4_addr"}, {"_v6", "ipv6_addr"}} {
+			fmt.Fprintf(w, "set %s%s {\n\ttype %s\n", name, set.suffix, set.typ)
+			if len(flags) != 0 {
+				fmt.Fprintf(w, "\tflags %s\n", strings.Join(flags, ", "))
+			}
+			if timeout != "" {
+				fmt.Fprintf(w, "\ttimeout %s\n", timeout)
+			}
+			fmt.Fprintf(w, "\tcomment %q\n}\n", "geosite:"+list.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteNftSet(t *testing.T) {
+	lists := []*ExportList{
+		testExportList(t, "cn@-ads",
+			"domain:example.cn",
+			"full:www.example.com",
+			"domain:example.com.cn",
+		),
+		testExportList(t, "geolocation-!cn", "keyword:google"),
+		testExportList(t, "apple", "domain:apple.com"),
+	}
+	var buf bytes.Buffer
+	if err := writeNftSet(&buf, lists, ExportOptions{"timeout:cn@-ads": "1h", "interval:cn@-ads": "true", "name:geolocation-!cn": "gfw", "interval:geolocation-!cn": "1"}); err != nil {
+		t.Fatalf("writeNftSet got unexpected error: %v", err)
+	}
+	want := `# geosite:cn@-ads
+define geosite_cn_no_ads = {
+	"example.cn",
+	"example.com.cn",
+	"www.example.com"
+}
+
+set geosite_cn_no_ads_v4 {
+	type ipv4_addr
+	flags interval, timeout
+	timeout 1h
+	comment "geosite:cn@-ads"
+}
+set geosite_cn_no_ads_v6 {
+	type ipv6_addr
+	flags interval, timeout
+	timeout 1h
+	comment "geosite:cn@-ads"
+}
+
+# geosite:geolocation-!cn
+set gfw_v4 {
+	type ipv4_addr
+	flags interval
+	comment "geosite:geolocation-!cn"
+}
+set gfw_v6 {
+	type ipv6_addr
+	flags interval
+	comment "geosite:geolocation-!cn"
+}
+
+# geosite:apple
+define geosite_apple = {
+	"apple.com"
+}
+
+set geosite_apple_v4 {
+	type ipv4_addr
+	comment "geosite:apple"
+}
+set geosite_apple_v6 {
+	type ipv6_addr
+	comment "geosite:apple"
+}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("writeNftSet() = %s, want %s", got, want)
+	}
+
+	for _, opts := range []ExportOptions{{"name": "1cn"}, {"timeout": "1 hour"}, {"interval": "yes"}} {
+		if err := writeNftSet(new(bytes.Buffer), lists, opts); err == nil {
+			t.Errorf("writeNftSet(%v) = nil error, want error", opts)
+		}
+	}
+	if got, want := nftSetName("tiktok@!cn@-ads"), "geosite_tiktok__cn_no_ads"; got != want {
+		t.Errorf("nftSetName() = %q, want %q", got, want)
+	}
+}
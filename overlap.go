@@ -0,0 +1,298 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/v2fly/domain-list-community/internal/dlc"
+)
+
+var (
+	overlapLists  = flag.String("overlaplists", "", "Lists to compute the overlaps between each other, separated by ',' comma")
+	disjointPairs = flag.String("disjointpairs", "", "Pairs of lists which must not overlap, separated by ',' comma, e.g. 'cn:geolocation-!cn'")
+)
+
+// Overlap is a pair of rules of two lists, which match at least one domain in
+// common.
+type Overlap struct {
+	A, B *Entry
+}
+
+// findOverlaps returns the overlaps between the rules of list a and list b,
+// sorted by the rules of list a, and the rules of both lists unchecked for
+// overlaps with some rules of the other list, sorted.
+//
+// Domain and full type rules overlap with domain-suffix semantics, so that
+// `domain:a.com` overlaps `domain:x.a.com` and `full:x.a.com`. Keyword and
+// regexp rules are checked by rulesOverlap, and the unchecked rules of a pair
+// are its regexp rules, or its keyword rules if it has no regexp rule.
+func findOverlaps(a, b []*Entry) (overlaps []Overlap, unchecked []*Entry, err error) {
+	seen := make(map[Overlap]bool)
+	add := func(x, y *Entry) {
+		if o := (Overlap{A: x, B: y}); !seen[o] {
+			seen[o] = true
+			overlaps = append(overlaps, o)
+		}
+	}
+
+	aIndex, bIndex := newDomainIndex(len(a)), newDomainIndex(len(b))
+	for _, entry := range a {
+		if entry.Type == dlc.RuleTypeDomain || entry.Type == dlc.RuleTypeFullDomain {
+			aIndex.insert(entry)
+		}
+	}
+	for _, entry := range b {
+		if entry.Type == dlc.RuleTypeDomain || entry.Type == dlc.RuleTypeFullDomain {
+			bIndex.insert(entry)
+		}
+	}
+	aIndex.sort()
+	bIndex.sort()
+	// Rules of list b covering rules of list a, and vice versa
+	for _, rule := range aIndex.rules {
+		bIndex.coveringRules(rule, func(covering *Entry) { add(rule, covering) })
+	}
+	for _, rule := range bIndex.rules {
+		aIndex.coveringRules(rule, func(covering *Entry) { add(covering, rule) })
+	}
+
+	// Keyword and regexp rules of list a against all rules of list b, and the
+	// ones of list b against the domain and full type rules of list a
+	regexps := make(map[string]*regexp.Regexp)
+	for _, entry := range slices.Concat(a, b) {
+		if entry.Type == dlc.RuleTypeRegexp && regexps[entry.Value] == nil {
+			if regexps[entry.Value], err = regexp.Compile(entry.Value); err != nil {
+				return nil, nil, fmt.Errorf("invalid regexp %q: %w", entry.Value, err)
+			}
+		}
+	}
+	check := func(x, y *Entry) {
+		if overlap, checked := rulesOverlap(x, y, regexps); overlap {
+			add(x, y)
+		} else if !checked {
+			hasRegexp := x.Type == dlc.RuleTypeRegexp || y.Type == dlc.RuleTypeRegexp
+			for _, entry := range []*Entry{x, y} {
+				if entry.Type == dlc.RuleTypeRegexp || (!hasRegexp && entry.Type == dlc.RuleTypeKeyword) {
+					unchecked = append(unchecked, entry)
+				}
+			}
+		}
+	}
+	for _, x := range a {
+		if x.Type == dlc.RuleTypeKeyword || x.Type == dlc.RuleTypeRegexp {
+			for _, y := range b {
+				check(x, y)
+			}
+		}
+	}
+	for _, y := range b {
+		if y.Type == dlc.RuleTypeKeyword || y.Type == dlc.RuleTypeRegexp {
+			for _, rule := range aIndex.rules {
+				check(rule, y)
+			}
+		}
+	}
+
+	slices.SortFunc(overlaps, func(x, y Overlap) int {
+		if c := strings.Compare(x.A.Plain, y.A.Plain); c != 0 {
+			return c
+		}
+		return strings.Compare(x.B.Plain, y.B.Plain)
+	})
+	slices.SortFunc(unchecked, func(x, y *Entry) int {
+		return strings.Compare(x.Plain, y.Plain)
+	})
+	return overlaps, slices.Compact(unchecked), nil
+}
+
+// coveringRules calls fn with every rule of the index which matches all the
+// domains matched by the domain or full type entry.
+func (x *domainIndex) coveringRules(entry *Entry, fn func(*Entry)) {
+	for domain, ok := entry.Value, true; ok; _, domain, ok = strings.Cut(domain, ".") {
+		for _, rule := range x.domainRules(domain) {
+			// A full type rule only covers the full type rule of the same domain
+			if rule.Type == dlc.RuleTypeDomain || (domain == entry.Value && entry.Type == dlc.RuleTypeFullDomain) {
+				fn(rule)
+			}
+		}
+	}
+}
+
+// keywordDomain returns a domain name containing the keyword, like "afooa" for
+// `keyword:foo`, or "" if no domain name contains the keyword.
+func keywordDomain(keyword string) string {
+	if domain := "a" + keyword + "a"; validateDomainName(domain) {
+		return domain
+	}
+	return ""
+}
+
+// rulesOverlap reports whether the rules, one of which is a keyword or regexp
+// type rule, match at least one domain in common, by matching them against a
+// domain matched by both of them, like "afooa.example.com" for `keyword:foo`
+// and `domain:example.com`. It also reports whether they are checked at all,
+// as a regexp is only checked against the full type rules and the domains of
+// other rules, and may overlap them in other ways.
+func rulesOverlap(x, y *Entry, regexps map[string]*regexp.Regexp) (overlap, checked bool) {
+	if y.Type == dlc.RuleTypeRegexp || (y.Type == dlc.RuleTypeKeyword && x.Type != dlc.RuleTypeRegexp) {
+		x, y = y, x
+	}
+	switch x.Type {
+	case dlc.RuleTypeKeyword:
+		if strings.Contains(y.Value, x.Value) || (y.Type == dlc.RuleTypeKeyword && strings.Contains(x.Value, y.Value)) {
+			return true, true
+		}
+		kdomain := keywordDomain(x.Value)
+		switch y.Type {
+		case dlc.RuleTypeFullDomain:
+			return false, true
+		case dlc.RuleTypeDomain:
+			if kdomain == "" {
+				return false, true
+			}
+			overlap = validateDomainName(kdomain + "." + y.Value)
+			return overlap, overlap // Too long otherwise, but a shorter domain may match
+		case dlc.RuleTypeKeyword:
+			ykdomain := keywordDomain(y.Value)
+			if kdomain == "" || ykdomain == "" {
+				return false, true
+			}
+			overlap = validateDomainName(kdomain + "." + ykdomain)
+			return overlap, overlap
+		}
+	case dlc.RuleTypeRegexp:
+		re := regexps[x.Value]
+		switch y.Type {
+		case dlc.RuleTypeFullDomain:
+			return re.MatchString(y.Value), true
+		case dlc.RuleTypeDomain:
+			overlap = re.MatchString(y.Value)
+			return overlap, overlap
+		case dlc.RuleTypeKeyword:
+			kdomain := keywordDomain(y.Value)
+			if kdomain == "" {
+				return false, true
+			}
+			overlap = re.MatchString(kdomain)
+			return overlap, overlap
+		case dlc.RuleTypeRegexp:
+			return false, false
+		}
+	}
+	return false, true
+}
+
+// reportOverlaps prints the matrix of the overlaps between the lists, with the
+// numbers of rules unchecked for overlaps after '+', and the detailed
+// overlapping rules and unchecked rules, and returns the number of disjoint
+// pairs which actually overlap.
+func (p *Processor) reportOverlaps(names []string, disjoint [][2]string) (int, error) {
+	for _, pair := range disjoint {
+		for _, name := range pair {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	entries := make([][]*Entry, len(names))
+	for i, name := range names {
+		pl, exist := p.parsedListByName[name]
+		if !exist {
+			return 0, fmt.Errorf("list %q not found", name)
+		}
+		entries[i] = pl.FinalEntries
+	}
+	overlaps := make(map[[2]string][]Overlap)
+	unchecked := make(map[[2]string][]*Entry)
+	for i := range names {
+		for j := i + 1; j < len(names); j++ {
+			found, notFound, err := findOverlaps(entries[i], entries[j])
+			if err != nil {
+				return 0, fmt.Errorf("failed to find overlaps between %q and %q: %w", names[i], names[j], err)
+			}
+			overlaps[[2]string{names[i], names[j]}] = found
+			overlaps[[2]string{names[j], names[i]}] = found
+			unchecked[[2]string{names[i], names[j]}] = notFound
+			unchecked[[2]string{names[j], names[i]}] = notFound
+		}
+	}
+
+	// Matrix of the numbers of overlaps
+	labels := make([]string, len(names))
+	width := 0
+	for i, name := range names {
+		labels[i] = fmt.Sprintf("[%d] %s", i+1, strings.ToLower(name))
+		width = max(width, len(labels[i]))
+	}
+	fmt.Printf("overlaps between %d lists:\n", len(names))
+	fmt.Printf("%*s", width, "")
+	for i := range names {
+		fmt.Printf(" %8s", fmt.Sprintf("[%d]", i+1))
+	}
+	fmt.Println()
+	for i, a := range names {
+		fmt.Printf("%-*s", width, labels[i])
+		for _, b := range names {
+			cell := "-"
+			if a != b {
+				cell = strconv.Itoa(len(overlaps[[2]string{a, b}]))
+				if n := len(unchecked[[2]string{a, b}]); n != 0 {
+					cell += "+" + strconv.Itoa(n)
+				}
+			}
+			fmt.Printf(" %8s", cell)
+		}
+		fmt.Println()
+	}
+	// Detailed overlapping rules
+	for i := range names {
+		for j := i + 1; j < len(names); j++ {
+			pair := [2]string{names[i], names[j]}
+			if found := overlaps[pair]; len(found) != 0 {
+				fmt.Printf("%d overlap(s) between %q and %q:\n", len(found), strings.ToLower(names[i]), strings.ToLower(names[j]))
+				for _, o := range found {
+					fmt.Printf("  %s <-> %s\n", o.A.Plain, o.B.Plain)
+				}
+			}
+			if notFound := unchecked[pair]; len(notFound) != 0 {
+				fmt.Printf("%d rule(s) of %q and %q unchecked for overlaps with some rules of the other list:\n", len(notFound), strings.ToLower(names[i]), strings.ToLower(names[j]))
+				for _, entry := range notFound {
+					fmt.Printf("  %s\n", entry.Plain)
+				}
+			}
+		}
+	}
+
+	violations := 0
+	for _, pair := range disjoint {
+		if n := len(overlaps[pair]); n != 0 {
+			fmt.Printf("[Error] lists %q and %q must be disjoint but have %d overlap(s)\n", strings.ToLower(pair[0]), strings.ToLower(pair[1]), n)
+			violations++
+		}
+		if n := len(unchecked[pair]); n != 0 {
+			warner.warnf(WarnOverlapUnchecked, "lists %q and %q must be disjoint but have %d rule(s) unchecked for overlaps", strings.ToLower(pair[0]), strings.ToLower(pair[1]), n)
+		}
+	}
+	return violations, nil
+}
+
+// parseDisjointPairs parses pairs of list names like `cn:geolocation-!cn`.
+func parseDisjointPairs(raw string) ([][2]string, error) {
+	var pairs [][2]string
+	for rawPair := range strings.SplitSeq(raw, ",") {
+		if rawPair = strings.TrimSpace(rawPair); rawPair == "" {
+			continue
+		}
+		a, b, ok := strings.Cut(rawPair, ":")
+		a, b = strings.ToUpper(strings.TrimSpace(a)), strings.ToUpper(strings.TrimSpace(b))
+		if !ok || !validateSiteName(a) || !validateSiteName(b) || a == b {
+			return nil, fmt.Errorf("invalid pair of lists: %q", rawPair)
+		}
+		pairs = append(pairs, [2]string{a, b})
+	}
+	return pairs, nil
+}
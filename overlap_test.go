@@ -0,0 +1,136 @@
+package main
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/v2fly/domain-list-community/internal/dlc"
+)
+
+func TestFindOverlaps(t *testing.T) {
+	parse := func(rules ...string) []*Entry {
+		entries := make([]*Entry, 0, len(rules))
+		for _, rule := range rules {
+			typ, value, _ := strings.Cut(rule, ":")
+			entry, _, err := parseEntry(typ, value)
+			if err != nil {
+				t.Fatalf("parseEntry(%q) got unexpected error: %v", rule, err)
+			}
+			entries = append(entries, entry)
+		}
+		return entries
+	}
+	a := parse(
+		"domain:a.com",
+		"full:www.b.com",
+		"full:c.com",
+		"domain:d.com @cn",
+		"keyword:tracker", // Matches every domain type rule of list b
+		`regexp:^ads\d+\.f\.com$`,
+		"domain:unrelated.org",
+	)
+	b := parse(
+		"full:x.a.com",       // Covered by domain:a.com
+		"domain:a.com",       // Same as domain:a.com
+		"domain:b.com",       // Covers full:www.b.com
+		"full:c.com",         // Same as full:c.com
+		"full:sub.c.com",     // Not covered by full:c.com
+		"domain:x.d.com",     // Covered by domain:d.com regardless of attributes
+		"full:tracker.e.com", // Matched by keyword:tracker
+		"domain:ads1.f.com",  // Matched by the regexp
+		"keyword:unrelated",  // Matches every domain type rule of list a, and keyword:tracker
+	)
+	overlaps, unchecked, err := findOverlaps(a, b)
+	if err != nil {
+		t.Fatalf("findOverlaps got unexpected error: %v", err)
+	}
+	got := make([]string, 0, len(overlaps))
+	for _, o := range overlaps {
+		got = append(got, o.A.Plain+" <-> "+o.B.Plain)
+	}
+
+	want := []string{
+		"domain:a.com <-> domain:a.com",
+		"domain:a.com <-> full:x.a.com",
+		"domain:a.com <-> keyword:unrelated",
+		"domain:d.com:@cn <-> domain:x.d.com",
+		"domain:d.com:@cn <-> keyword:unrelated",
+		"domain:unrelated.org <-> keyword:unrelated",
+		"full:c.com <-> full:c.com",
+		"full:www.b.com <-> domain:b.com",
+		"keyword:tracker <-> domain:a.com",
+		"keyword:tracker <-> domain:ads1.f.com",
+		"keyword:tracker <-> domain:b.com",
+		"keyword:tracker <-> domain:x.d.com",
+		"keyword:tracker <-> full:tracker.e.com",
+		"keyword:tracker <-> keyword:unrelated",
+		`regexp:^ads\d+\.f\.com$ <-> domain:ads1.f.com`,
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("findOverlaps() = %q, want %q", got, want)
+	}
+	got = got[:0]
+	for _, entry := range unchecked {
+		got = append(got, entry.Plain)
+	}
+	want = []string{`regexp:^ads\d+\.f\.com$`} // Against domain:a.com, domain:b.com, domain:x.d.com and keyword:unrelated
+	if !slices.Equal(got, want) {
+		t.Errorf("findOverlaps() unchecked = %q, want %q", got, want)
+	}
+}
+
+func TestRulesOverlap(t *testing.T) {
+	testCases := []struct {
+		x, y             string
+		overlap, checked bool
+	}{
+		{"keyword:foo", "domain:a.com", true, true}, // afooa.a.com
+		{"domain:a.com", "keyword:foo", true, true},
+		{"keyword:foo", "full:a.com", false, true},
+		{"keyword:foo", "full:foo.a.com", true, true},
+		{"keyword:a..b", "domain:a.com", false, true}, // No domain contains it
+		{"keyword:" + strings.Repeat("x", 60), "domain:" + strings.Repeat("y.", 100) + "com", false, false},
+		{"keyword:foo", "keyword:bar", true, true}, // afooa.abara
+		{"keyword:foo", "keyword:a..b", false, true},
+		{"keyword:foo", "regexp:foo", true, true},
+		{"regexp:^foo", "keyword:foo", false, false},
+		{`regexp:^ads\d+\.f\.com$`, "full:ads1.f.com", true, true},
+		{`regexp:^ads\d+\.f\.com$`, "full:x.f.com", false, true},
+		{`regexp:^ads\d+\.f\.com$`, "domain:ads1.f.com", true, true},
+		{`regexp:^ads\d+\.f\.com$`, "domain:f.com", false, false},
+		{"regexp:foo", "regexp:bar", false, false},
+	}
+	regexps := make(map[string]*regexp.Regexp)
+	for _, tc := range testCases {
+		var entries [2]*Entry
+		for i, rule := range []string{tc.x, tc.y} {
+			typ, value, _ := strings.Cut(rule, ":")
+			entries[i] = &Entry{Type: typ, Value: value, Plain: rule}
+			if typ == dlc.RuleTypeRegexp {
+				regexps[value] = regexp.MustCompile(value)
+			}
+		}
+		overlap, checked := rulesOverlap(entries[0], entries[1], regexps)
+		if overlap != tc.overlap || checked != tc.checked {
+			t.Errorf("rulesOverlap(%q, %q) = %v, %v, want %v, %v", tc.x, tc.y, overlap, checked, tc.overlap, tc.checked)
+		}
+	}
+}
+
+func TestParseDisjointPairs(t *testing.T) {
+	pairs, err := parseDisjointPairs(" cn:geolocation-!cn , google:Apple")
+	if err != nil {
+		t.Fatalf("parseDisjointPairs got unexpected error: %v", err)
+	}
+	want := [][2]string{{"CN", "GEOLOCATION-!CN"}, {"GOOGLE", "APPLE"}}
+	if !slices.Equal(pairs, want) {
+		t.Errorf("parseDisjointPairs() = %v, want %v", pairs, want)
+	}
+	for _, raw := range []string{"cn", "cn:cn", "cn:", "cn:geo@cn"} {
+		if _, err := parseDisjointPairs(raw); err == nil {
+			t.Errorf("parseDisjointPairs(%q) = nil, want error", raw)
+		}
+	}
+}
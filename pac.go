@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/v2fly/domain-list-community/internal/dlc"
+)
+
+const defaultPACProxy = "DIRECT"
+
+// pacRules is the data of a PAC file, where rules refer to the lists they
+// come from by index, and lists refer to their proxies by index. Domain and
+// full type rules are objects keyed by domains, so that FindProxyForURL looks
+// up the suffixes of a host instead of trying every rule.
+type pacRules struct {
+	Proxies  []string       `json:"proxies"`
+	Lists    []int          `json:"lists"` // Proxies of the lists
+	Default  string         `json:"default"`
+	Domains  map[string]int `json:"domains"`
+	Fulls    map[string]int `json:"fulls"`
+	Keywords []pacPattern   `json:"keywords"` // In the order of the lists
+	Regexps  []pacPattern   `json:"regexps"`  // In the order of the lists
+}
+
+type pacPattern struct {
+	Pattern string `json:"pattern"`
+	List    int    `json:"list"`
+}
+
+// pacScript finds the proxy of the first list with a rule matching the host,
+// among the rules of the exact host, of its suffixes, and the keywords and
+// regexps which come before the list found so far.
+const pacScript = `var hasOwn = Object.prototype.hasOwnProperty;
+var regexps = [];
+for (var i = 0; i < rules.regexps.length; i++) {
+  regexps.push(new RegExp(rules.regexps[i].pattern));
+}
+
+function FindProxyForURL(url, host) {
+  host = host.toLowerCase();
+  if (host.charAt(host.length - 1) === ".") {
+    host = host.substring(0, host.length - 1);
+  }
+  var list = hasOwn.call(rules.fulls, host) ? rules.fulls[host] : rules.lists.length;
+  for (var suffix = host; ; suffix = suffix.substring(suffix.indexOf(".") + 1)) {
+    if (hasOwn.call(rules.domains, suffix) && rules.domains[suffix] < list) {
+      list = rules.domains[suffix];
+    }
+    if (suffix.indexOf(".") < 0) {
+      break;
+    }
+  }
+  for (var i = 0; i < rules.keywords.length && rules.keywords[i].list < list; i++) {
+    if (host.indexOf(rules.keywords[i].pattern) >= 0) {
+      list = rules.keywords[i].list;
+      break;
+    }
+  }
+  for (var i = 0; i < regexps.length && rules.regexps[i].list < list; i++) {
+    if (regexps[i].test(host)) {
+      list = rules.regexps[i].list;
+      break;
+    }
+  }
+  return list < rules.lists.length ? rules.proxies[rules.lists[list]] : rules["default"];
+}
+`
+
+// jsIncompatibleRegexp matches the syntax of Go regexps which JavaScript
+// regexps lack or treat differently, like flags and Unicode classes.
+var jsIncompatibleRegexp = regexp.MustCompile(`\(\?[^:]|\\[APQpz]|\[\[:`)
+
+// newPACRules returns the data of the PAC file of the lists with the `proxy`
+// option of each list, like `PROXY 127.0.0.1:8080; DIRECT`, and the `default`
+// option for hosts matching no rule. The first list of a rule wins, and so
+// does the first list matching a host.
+func newPACRules(lists []*ExportList, opts ExportOptions) (*pacRules, error) {
+	rules := &pacRules{
+		Default: opts.get("default", defaultPACProxy),
+		Domains: make(map[string]int),
+		Fulls:   make(map[string]int),
+		// Empty slices are encoded as empty arrays for the script
+		Lists:    []int{},
+		Keywords: []pacPattern{},
+		Regexps:  []pacPattern{},
+	}
+	if rules.Default == "" || strings.ContainsAny(rules.Default, "\r\n") {
+		return nil, fmt.Errorf("invalid default proxy %q", rules.Default)
+	}
+	proxyIdx := make(map[string]int)
+	seen := make(map[string]bool)
+	for i, list := range lists {
+		proxy := opts.getFor("proxy", list.Name, "")
+		if proxy == "" || strings.ContainsAny(proxy, "\r\n") {
+			return nil, fmt.Errorf("invalid proxy %q of list %q", proxy, list.Name)
+		}
+		idx, exist := proxyIdx[proxy]
+		if !exist {
+			idx = len(rules.Proxies)
+			proxyIdx[proxy] = idx
+			rules.Proxies = append(rules.Proxies, proxy)
+		}
+		rules.Lists = append(rules.Lists, idx)
+		var dropped []*Entry
+		for _, entry := range stripAttrs(list.Entries) {
+			if seen[entry.Plain] {
+				continue
+			}
+			seen[entry.Plain] = true
+			switch entry.Type {
+			case dlc.RuleTypeDomain:
+				rules.Domains[entry.Value] = i
+			case dlc.RuleTypeFullDomain:
+				rules.Fulls[entry.Value] = i
+			case dlc.RuleTypeKeyword:
+				rules.Keywords = append(rules.Keywords, pacPattern{Pattern: entry.Value, List: i})
+			case dlc.RuleTypeRegexp:
+				if jsIncompatibleRegexp.MatchString(entry.Value) {
+					dropped = append(dropped, entry)
+					continue
+				}
+				rules.Regexps = append(rules.Regexps, pacPattern{Pattern: entry.Value, List: i})
+			}
+		}
+		warnDropped("pac", []*ExportList{list}, dropped)
+	}
+	return rules, nil
+}
+
+// writePAC writes the lists as a proxy auto-config file.
+func writePAC(w io.Writer, lists []*ExportList, opts ExportOptions) error {
+	rules, err := newPACRules(lists, opts)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(rules)
+	if err != nil {
+		return fmt.Errorf("failed to encode PAC rules: %w", err)
+	}
+	fmt.Fprintf(w, "var rules = %s;\n\n%s", data, pacScript)
+	return nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os/exec"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// findProxy evaluates the rules of a PAC file like its FindProxyForURL.
+func (r *pacRules) findProxy(host string) string {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	list, ok := r.Fulls[host]
+	if !ok {
+		list = len(r.Lists)
+	}
+	for suffix := host; ; {
+		if idx, ok := r.Domains[suffix]; ok && idx < list {
+			list = idx
+		}
+		var found bool
+		if _, suffix, found = strings.Cut(suffix, "."); !found {
+			break
+		}
+	}
+	for _, keyword := range r.Keywords {
+		if keyword.List < list && strings.Contains(host, keyword.Pattern) {
+			list = keyword.List
+			break
+		}
+	}
+	for _, re := range r.Regexps {
+		if re.List < list && regexp.MustCompile(re.Pattern).MatchString(host) {
+			list = re.List
+			break
+		}
+	}
+	if list == len(r.Lists) {
+		return r.Default
+	}
+	return r.Proxies[r.Lists[list]]
+}
+
+// runPACScript runs FindProxyForURL of the PAC file by Node.js for the hosts.
+func runPACScript(t *testing.T, pac string, hosts []string) []string {
+	t.Helper()
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node is not found to run the PAC file")
+	}
+	data, err := json.Marshal(hosts)
+	if err != nil {
+		t.Fatalf("failed to encode hosts: %v", err)
+	}
+	cmd := exec.Command(node)
+	cmd.Stdin = strings.NewReader(pac + "\nconsole.log(JSON.stringify(" + string(data) +
+		".map(function (host) { return FindProxyForURL(\"https://\" + host + \"/\", host); })));\n")
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("failed to run the PAC file by node: %v", err)
+	}
+	var proxies []string
+	if err := json.Unmarshal(out, &proxies); err != nil {
+		t.Fatalf("failed to decode the output %s of node: %v", out, err)
+	}
+	return proxies
+}
+
+func TestWritePAC(t *testing.T) {
+	lists := []*ExportList{
+		testExportList(t, "category-ads",
+			"domain:ads.example.com",
+			"full:tracker.example.org",
+			"keyword:doubleclick",
+			`regexp:^ad\d+\.example\.net$`,
+			`regexp:(?i)^AD\.example\.net$`, // Dropped
+		),
+		testExportList(t, "cn",
+			"domain:example.cn",
+			"domain:ads.example.com", // The first list wins
+			"keyword:baidu",
+		),
+		testExportList(t, "gfw",
+			"domain:example.com",
+			"domain:example.org",
+			"domain:example.net",
+		),
+	}
+	opts := ExportOptions{
+		"proxy:category-ads": "PROXY 127.0.0.1:9",
+		"proxy:cn":           "DIRECT",
+		"proxy":              "SOCKS5 127.0.0.1:1080; DIRECT",
+		"default":            "PROXY 127.0.0.1:8080",
+	}
+	var buf bytes.Buffer
+	if err := writePAC(&buf, lists, opts); err != nil {
+		t.Fatalf("writePAC got unexpected error: %v", err)
+	}
+	data, script, ok := strings.Cut(strings.TrimPrefix(buf.String(), "var rules = "), ";\n\n")
+	if !ok || script != pacScript {
+		t.Fatalf("writePAC() = %s, want rules followed by the script", buf.String())
+	}
+	rules := new(pacRules)
+	if err := json.Unmarshal([]byte(data), rules); err != nil {
+		t.Fatalf("failed to decode rules %s: %v", data, err)
+	}
+
+	testCases := []struct{ host, want string }{
+		{"ads.example.com", "PROXY 127.0.0.1:9"},
+		{"x.ads.example.com.", "PROXY 127.0.0.1:9"},
+		{"www.example.com", "SOCKS5 127.0.0.1:1080; DIRECT"},
+		{"tracker.example.org", "PROXY 127.0.0.1:9"},
+		{"TRACKER.example.org", "PROXY 127.0.0.1:9"},
+		{"x.tracker.example.org", "SOCKS5 127.0.0.1:1080; DIRECT"},
+		{"doubleclick.net", "PROXY 127.0.0.1:9"},
+		{"doubleclick.example.com", "PROXY 127.0.0.1:9"}, // The keyword of an earlier list wins
+		{"doubleclick.example.cn", "PROXY 127.0.0.1:9"},
+		{"baidu.example.com", "DIRECT"},
+		{"baidu.ads.example.com", "PROXY 127.0.0.1:9"},
+		{"ad12.example.net", "PROXY 127.0.0.1:9"}, // The regexp of an earlier list wins
+		{"ad.example.net", "SOCKS5 127.0.0.1:1080; DIRECT"},
+		{"www.example.cn", "DIRECT"},
+		{"example", "PROXY 127.0.0.1:8080"},
+	}
+	hosts := make([]string, len(testCases))
+	for i, tc := range testCases {
+		hosts[i] = tc.host
+		if got := rules.findProxy(tc.host); got != tc.want {
+			t.Errorf("findProxy(%q) = %q, want %q", tc.host, got, tc.want)
+		}
+	}
+	for i, got := range runPACScript(t, buf.String(), hosts) {
+		if tc := testCases[i]; got != tc.want {
+			t.Errorf("FindProxyForURL(%q) = %q, want %q", tc.host, got, tc.want)
+		}
+	}
+
+	buf.Reset()
+	if err := writePAC(&buf, lists[2:], opts); err != nil {
+		t.Fatalf("writePAC got unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"keywords":[],"regexps":[]`) {
+		t.Errorf("writePAC() = %s, want empty arrays of keywords and regexps", buf.String())
+	}
+
+	for _, opts := range []ExportOptions{
+		{"proxy:category-ads": "DIRECT"},
+		{"proxy": "DIRECT", "default": ""},
+	} {
+		if err := writePAC(new(bytes.Buffer), lists, opts); err == nil {
+			t.Errorf("writePAC(%v) = nil error, want error", opts)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/v2fly/domain-list-community/internal/dlc"
+)
+
+const defaultQuanXPolicy = "proxy"
+
+// writeQuanX writes the lists as a Quantumult X filter, where every rule ends
+// with the policy of its list. The rules of a list are kept even if they are
+// covered by the rules of another list, which may have another policy.
+func writeQuanX(w io.Writer, lists []*ExportList, opts ExportOptions) error {
+	seen := make(map[string]bool)
+	for _, list := range lists {
+		policy := opts.getFor("policy", list.Name, defaultQuanXPolicy)
+		if policy == "" || strings.ContainsAny(policy, ",\r\n") {
+			return fmt.Errorf("invalid policy %q of list %q", policy, list.Name)
+		}
+		var dropped []*Entry
+		for _, entry := range stripAttrs(list.Entries) {
+			var rule string
+			switch entry.Type {
+			case dlc.RuleTypeDomain:
+				rule = "HOST-SUFFIX," + entry.Value
+			case dlc.RuleTypeFullDomain:
+				rule = "HOST," + entry.Value
+			case dlc.RuleTypeKeyword:
+				rule = "HOST-KEYWORD," + entry.Value
+			default:
+				dropped = append(dropped, entry)
+				continue
+			}
+			if !seen[rule] { // The first policy of a rule wins
+				seen[rule] = true
+				fmt.Fprintf(w, "%s,%s\n", rule, policy)
+			}
+		}
+		warnDropped("quanx", []*ExportList{list}, dropped)
+	}
+	return nil
+}
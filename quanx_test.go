@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteQuanX(t *testing.T) {
+	lists := []*ExportList{
+		testExportList(t, "cn",
+			"domain:example.cn",
+			"full:www.example.com @cn",
+			"keyword:baidu",
+			`regexp:^ads\d\.example\.cn$`, // Dropped
+		),
+		testExportList(t, "google@ads",
+			"domain:example.com @ads",
+			"full:www.example.com @ads", // Redundant
+			"keyword:baidu @ads",        // Duplicated
+		),
+		testExportList(t, "other", "domain:example.org"),
+	}
+	opts := ExportOptions{"policy": "Proxy", "policy:cn": "direct", "policy:google@ads": "reject"}
+	var buf bytes.Buffer
+	if err := writeQuanX(&buf, lists, opts); err != nil {
+		t.Fatalf("writeQuanX got unexpected error: %v", err)
+	}
+	want := "HOST-SUFFIX,example.cn,direct\n" +
+		"HOST,www.example.com,direct\n" +
+		"HOST-KEYWORD,baidu,direct\n" +
+		"HOST-SUFFIX,example.com,reject\n" +
+		"HOST-SUFFIX,example.org,Proxy\n"
+	if got := buf.String(); got != want {
+		t.Errorf("writeQuanX() = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	if err := writeQuanX(&buf, lists[2:], nil); err != nil {
+		t.Fatalf("writeQuanX got unexpected error: %v", err)
+	}
+	if got, want := buf.String(), "HOST-SUFFIX,example.org,proxy\n"; got != want {
+		t.Errorf("writeQuanX() with default policy = %q, want %q", got, want)
+	}
+
+	if err := writeQuanX(&buf, lists[2:], ExportOptions{"policy": "a,b"}); err == nil {
+		t.Error("writeQuanX() with policy \"a,b\" = nil error, want invalid policy error")
+	}
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"fmt"
+	"regexp/syntax"
+	"strings"
+	"unicode"
+)
+
+// Anchors of the start of a domain regexp
+const (
+	anchorNone  = iota // Matches anywhere in the domain
+	anchorStart        // `^`, matches from the start of the domain
+	anchorLabel        // `(^|\.)`, matches from the start of a label
+)
+
+// hostRegexp is a domain regexp split into its leading and trailing anchors
+// and the body between them, so that it can be translated into the wildcards
+// and URL regexps of other clients.
+type hostRegexp struct {
+	start int
+	body  []*syntax.Regexp
+	end   bool // Whether the body matches till the end of the domain
+}
+
+// hostChars are the characters which can appear in a domain.
+var hostChars = []rune{'-', '.', '0', '9', 'A', 'Z', '_', '_', 'a', 'z'}
+
+// parseHostRegexp parses a domain regexp, and reports whether its anchors are
+// only at the start and the end, which is the case of most regexp rules.
+func parseHostRegexp(expr string) (*hostRegexp, bool) {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return nil, false
+	}
+	hr := &hostRegexp{body: []*syntax.Regexp{re}}
+	if re.Op == syntax.OpConcat {
+		hr.body = re.Sub
+	}
+	if len(hr.body) != 0 {
+		if first := hr.body[0]; isBeginAnchor(first) {
+			hr.start, hr.body = anchorStart, hr.body[1:]
+		} else if isLabelAnchor(first) {
+			hr.start, hr.body = anchorLabel, hr.body[1:]
+		}
+	}
+	if n := len(hr.body); n != 0 && isEndAnchor(hr.body[n-1]) {
+		hr.end, hr.body = true, hr.body[:n-1]
+	}
+	for _, sub := range hr.body {
+		if hasAnchor(sub) {
+			return nil, false
+		}
+	}
+	return hr, true
+}
+
+func isBeginAnchor(re *syntax.Regexp) bool {
+	return re.Op == syntax.OpBeginText || re.Op == syntax.OpBeginLine
+}
+
+func isEndAnchor(re *syntax.Regexp) bool {
+	return re.Op == syntax.OpEndText || re.Op == syntax.OpEndLine
+}
+
+// isLabelAnchor reports whether the regexp is `(^|\.)` or `(?:^|\.)`.
+func isLabelAnchor(re *syntax.Regexp) bool {
+	if re.Op == syntax.OpCapture {
+		re = re.Sub[0]
+	}
+	if re.Op != syntax.OpAlternate || len(re.Sub) != 2 {
+		return false
+	}
+	isDot := func(re *syntax.Regexp) bool {
+		return re.Op == syntax.OpLiteral && string(re.Rune) == "." && re.Flags&syntax.FoldCase == 0
+	}
+	return (isBeginAnchor(re.Sub[0]) && isDot(re.Sub[1])) || (isDot(re.Sub[0]) && isBeginAnchor(re.Sub[1]))
+}
+
+func hasAnchor(re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpBeginText, syntax.OpBeginLine, syntax.OpEndText, syntax.OpEndLine,
+		syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+		return true
+	}
+	for _, sub := range re.Sub {
+		if hasAnchor(sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// wildcard returns the body as a wildcard pattern, where '?' matches a
+// character and '*' matches any characters, if it is exactly equivalent.
+func (hr *hostRegexp) wildcard() (string, bool) {
+	var sb strings.Builder
+	for _, sub := range hr.body {
+		switch {
+		case sub.Op == syntax.OpLiteral && sub.Flags&syntax.FoldCase == 0:
+			for _, r := range sub.Rune {
+				if r == '*' || r == '?' {
+					return "", false
+				}
+				sb.WriteRune(r)
+			}
+		case isAnyChar(sub):
+			sb.WriteByte('?')
+		case sub.Op == syntax.OpStar && isAnyChar(sub.Sub[0]):
+			sb.WriteByte('*')
+		case sub.Op == syntax.OpPlus && isAnyChar(sub.Sub[0]):
+			sb.WriteString("?*")
+		default:
+			return "", false
+		}
+	}
+	return sb.String(), true
+}
+
+func isAnyChar(re *syntax.Regexp) bool {
+	return re.Op == syntax.OpAnyChar || re.Op == syntax.OpAnyCharNotNL
+}
+
+// urlRegexp returns a regexp matching the URLs whose host is matched by the
+// domain regexp. The characters of the body are confined to the ones of a
+// domain, so that it never matches across the host.
+func (hr *hostRegexp) urlRegexp() string {
+	var sb strings.Builder
+	sb.WriteString(`^https?://`)
+	switch hr.start {
+	case anchorNone:
+		sb.WriteString(`[-.0-9A-Z_a-z]*`)
+	case anchorLabel:
+		sb.WriteString(`(?:[-.0-9A-Z_a-z]*\.)?`)
+	}
+	for _, sub := range hr.body {
+		sb.WriteString(confineToHost(sub).String())
+	}
+	if hr.end {
+		sb.WriteString(`(?::\d+)?(?:[/?#]|$)`)
+	}
+	return sb.String()
+}
+
+// confineToHost returns a copy of the regexp whose character classes only
+// contain the characters of a domain.
+func confineToHost(re *syntax.Regexp) *syntax.Regexp {
+	confined := *re
+	switch re.Op {
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		confined.Op, confined.Rune = syntax.OpCharClass, hostChars
+	case syntax.OpCharClass:
+		confined.Rune = intersectRanges(re.Rune, hostChars)
+	}
+	if len(re.Sub) != 0 {
+		confined.Sub = make([]*syntax.Regexp, len(re.Sub))
+		for i, sub := range re.Sub {
+			confined.Sub[i] = confineToHost(sub)
+		}
+	}
+	return &confined
+}
+
+// intersectRanges returns the intersection of two sorted rune range lists,
+// each of which is a flat list of lo-hi pairs like a syntax.OpCharClass.
+func intersectRanges(a, b []rune) []rune {
+	var ranges []rune
+	for i := 0; i < len(a); i += 2 {
+		for j := 0; j < len(b); j += 2 {
+			if lo, hi := max(a[i], b[j]), min(a[i+1], b[j+1]); lo <= hi {
+				ranges = append(ranges, lo, hi)
+			}
+		}
+	}
+	return ranges
+}
+
+// posixRegexp returns the regexp in POSIX extended syntax, which tools like
+// Squid use, if it has an equivalent. Non-greedy repetitions become greedy
+// ones, which match the same strings.
+func posixRegexp(expr string) (string, bool) {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return "", false
+	}
+	var sb strings.Builder
+	if !writePosix(&sb, re) {
+		return "", false
+	}
+	return sb.String(), true
+}
+
+func writePosix(sb *strings.Builder, re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpLiteral:
+		if re.Flags&syntax.FoldCase != 0 {
+			return false
+		}
+		for _, r := range re.Rune {
+			if strings.ContainsRune(`.[]()*+?{}|^$\`, r) {
+				sb.WriteByte('\\')
+			}
+			sb.WriteRune(r)
+		}
+	case syntax.OpCharClass:
+		return writePosixClass(sb, re.Rune)
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		sb.WriteByte('.')
+	case syntax.OpBeginLine, syntax.OpBeginText:
+		sb.WriteByte('^')
+	case syntax.OpEndLine, syntax.OpEndText:
+		sb.WriteByte('$')
+	case syntax.OpCapture:
+		sb.WriteByte('(')
+		if !writePosix(sb, re.Sub[0]) {
+			return false
+		}
+		sb.WriteByte(')')
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		sub := re.Sub[0]
+		group := sub.Op == syntax.OpConcat || sub.Op == syntax.OpAlternate ||
+			(sub.Op == syntax.OpLiteral && len(sub.Rune) > 1) ||
+			sub.Op == syntax.OpStar || sub.Op == syntax.OpPlus || sub.Op == syntax.OpQuest || sub.Op == syntax.OpRepeat
+		if group {
+			sb.WriteByte('(')
+		}
+		if !writePosix(sb, sub) {
+			return false
+		}
+		if group {
+			sb.WriteByte(')')
+		}
+		switch re.Op {
+		case syntax.OpStar:
+			sb.WriteByte('*')
+		case syntax.OpPlus:
+			sb.WriteByte('+')
+		case syntax.OpQuest:
+			sb.WriteByte('?')
+		case syntax.OpRepeat:
+			switch {
+			case re.Max == -1:
+				fmt.Fprintf(sb, "{%d,}", re.Min)
+			case re.Min == re.Max:
+				fmt.Fprintf(sb, "{%d}", re.Min)
+			default:
+				fmt.Fprintf(sb, "{%d,%d}", re.Min, re.Max)
+			}
+		}
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			group := sub.Op == syntax.OpAlternate
+			if group {
+				sb.WriteByte('(')
+			}
+			if !writePosix(sb, sub) {
+				return false
+			}
+			if group {
+				sb.WriteByte(')')
+			}
+		}
+	case syntax.OpAlternate:
+		for i, sub := range re.Sub {
+			if i != 0 {
+				sb.WriteByte('|')
+			}
+			if !writePosix(sb, sub) {
+				return false
+			}
+		}
+	default: // Empty matches, word boundaries and so on
+		return false
+	}
+	return true
+}
+
+// writePosixClass writes the character class of the rune ranges in POSIX
+// bracket syntax, where backslashes are literal, ']' must come first and '-'
+// must come last.
+func writePosixClass(sb *strings.Builder, ranges []rune) bool {
+	negated := len(ranges) != 0 && ranges[0] == 0 && ranges[len(ranges)-1] == unicode.MaxRune
+	if negated {
+		ranges = complementRanges(ranges)
+	}
+	if len(ranges) == 0 {
+		return false
+	}
+	var body strings.Builder
+	var bracket, dash, caret bool
+	for i := 0; i < len(ranges); i += 2 {
+		lo, hi := ranges[i], ranges[i+1]
+		for _, special := range []rune{']', '-', '^', '['} {
+			if lo < special && special < hi || lo != hi && (lo == special || hi == special) {
+				return false // Ranges from or to special characters are never needed in domains
+			}
+		}
+		switch {
+		case lo == ']':
+			bracket = true
+		case lo == '-':
+			dash = true
+		case lo == '^':
+			caret = true
+		case lo == '[':
+			return false // `[:`, `[.` and `[=` start other expressions
+		case lo == hi:
+			body.WriteRune(lo)
+		default:
+			body.WriteRune(lo)
+			body.WriteByte('-')
+			body.WriteRune(hi)
+		}
+	}
+	if caret {
+		if body.Len() == 0 && !bracket {
+			switch {
+			case dash: // `[-^]` or `[^-^]`
+				body.WriteByte('-')
+				dash = false
+			case negated:
+				sb.WriteString(`[^^]`)
+				return true
+			default:
+				sb.WriteString(`\^`)
+				return true
+			}
+		}
+		body.WriteByte('^') // Never first as the body or ']' precedes it
+	}
+	sb.WriteByte('[')
+	if negated {
+		sb.WriteByte('^')
+	}
+	if bracket {
+		sb.WriteByte(']')
+	}
+	sb.WriteString(body.String())
+	if dash {
+		sb.WriteByte('-')
+	}
+	sb.WriteByte(']')
+	return true
+}
+
+// complementRanges returns the complement of the sorted rune ranges.
+func complementRanges(ranges []rune) []rune {
+	var complement []rune
+	next := rune(0)
+	for i := 0; i < len(ranges); i += 2 {
+		if ranges[i] > next {
+			complement = append(complement, next, ranges[i]-1)
+		}
+		next = ranges[i+1] + 1
+	}
+	if next <= unicode.MaxRune {
+		complement = append(complement, next, unicode.MaxRune)
+	}
+	return complement
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestHostRegexpWildcard(t *testing.T) {
+	testCases := []struct {
+		expr    string
+		start   int
+		end     bool
+		pattern string
+		ok      bool
+	}{
+		{`^nis.+\.10010\.com$`, anchorStart, true, "nis?*.10010.com", true},
+		{`(^|\.)example\.com$`, anchorLabel, true, "example.com", true},
+		{`(?:\.|^)a.b.*c$`, anchorLabel, true, "a?b*c", true},
+		{`example\.com`, anchorNone, false, "example.com", true},
+		{`^ads[0-9]\.example\.com$`, anchorStart, true, "", false},
+		{`^a\*b$`, anchorStart, true, "", false},
+	}
+	for _, tc := range testCases {
+		hr, ok := parseHostRegexp(tc.expr)
+		if !ok {
+			t.Errorf("parseHostRegexp(%q) failed", tc.expr)
+			continue
+		}
+		if hr.start != tc.start || hr.end != tc.end {
+			t.Errorf("parseHostRegexp(%q) anchors = %d, %t, want %d, %t", tc.expr, hr.start, hr.end, tc.start, tc.end)
+		}
+		if pattern, ok := hr.wildcard(); pattern != tc.pattern || ok != tc.ok {
+			t.Errorf("wildcard(%q) = %q, %t, want %q, %t", tc.expr, pattern, ok, tc.pattern, tc.ok)
+		}
+	}
+
+	for _, expr := range []string{`^a$|^b$`, `a\bb$`, `(`} {
+		if _, ok := parseHostRegexp(expr); ok {
+			t.Errorf("parseHostRegexp(%q) succeeded, want failure", expr)
+		}
+	}
+}
+
+// TestHostRegexpURL makes sure that a URL regexp matches the URL of a host iff
+// the domain regexp matches the host.
+func TestHostRegexpURL(t *testing.T) {
+	exprs := []string{
+		`(^|\.)18j[efg]\.life$`,
+		`^[0-9]+vod-adaptive\.akamaized\.net$`,
+		`.+\.dkr\.ecr\.[^\.]+\.amazonaws\.com$`,
+		`^r+[0-9]+(---|\.)sn-(2x3|ni5|j5o)\w{5}\.googlevideo\.com$`,
+		`javdb\d+\.com$`,
+		`^nis.+\.10010\.com`,
+		`tracker`,
+	}
+	hosts := []string{
+		"18je.life", "www.18jf.life", "x18je.life", "18je.life.evil.com",
+		"12vod-adaptive.akamaized.net", "vod-adaptive.akamaized.net",
+		"a.dkr.ecr.us-east-1.amazonaws.com", "dkr.ecr.x.amazonaws.com", "a.dkr.ecr.b.c.amazonaws.com",
+		"r1---sn-2x3abcde.googlevideo.com", "rr2.sn-ni5xxxxx.googlevideo.com", "r1---sn-abcabcde.googlevideo.com",
+		"javdb12.com", "www.javdb3.com", "javdb.com",
+		"nisx.10010.com", "nis.10010.com", "nisa.10010.com.cn",
+		"a.tracker.net", "example.com",
+	}
+	for _, expr := range exprs {
+		hr, ok := parseHostRegexp(expr)
+		if !ok {
+			t.Fatalf("parseHostRegexp(%q) failed", expr)
+		}
+		hostRe := regexp.MustCompile(expr)
+		urlRe, err := regexp.Compile(hr.urlRegexp())
+		if err != nil {
+			t.Fatalf("urlRegexp(%q) = %q is invalid: %v", expr, hr.urlRegexp(), err)
+		}
+		for _, host := range hosts {
+			want := hostRe.MatchString(host)
+			for _, url := range []string{"https://" + host + "/", "http://" + host + ":8080/x", "https://" + host} {
+				if got := urlRe.MatchString(url); got != want {
+					t.Errorf("urlRegexp(%q) = %q matches %q: %t, want %t", expr, urlRe, url, got, want)
+				}
+			}
+		}
+		// Never matches across the host
+		for _, url := range []string{"https://evil.com/18je.life", "https://evil.com/?a.dkr.ecr.x.amazonaws.com"} {
+			if hostRe.MatchString(url[len("https://evil.com"):]) && urlRe.MatchString(url) {
+				t.Errorf("urlRegexp(%q) = %q matches %q", expr, urlRe, url)
+			}
+		}
+	}
+}
+
+// TestPosixRegexp checks the POSIX regexps against the original ones on
+// sample domains.
+func TestPosixRegexp(t *testing.T) {
+	testCases := []struct {
+		expr, want string
+	}{
+		{`^ads\d+\.example\.com$`, `^ads[0-9]+\.example\.com$`},
+		{`(^|\.)ex(?:am)+ple\.com$`, `(^|\.)ex(am)+ple\.com$`},
+		{`^[^.]*\.example\.org$`, `^[^.]*\.example\.org$`},
+		{`^a-?b[-_]{2,}c[^-^]x*?\.net$`, `^a-?b[_-]{2,}c[^-^]x*\.net$`},
+		{`^(www|cdn)[1-3]?\.example\.(com|net)$`, `^(www|cdn)[1-3]?\.example\.(com|net)$`},
+	}
+	samples := []string{
+		"ads1.example.com", "ads.example.com", "x.ads12.example.com", "example.com", "exampleple.com",
+		"a.examample.com", "www.example.org", "a.b.example.org", "ab--c.net", "a-b__cdxx.net",
+		"ab-_c^.net", "www.example.com", "cdn3.example.net", "www4.example.com",
+	}
+	for _, tc := range testCases {
+		got, ok := posixRegexp(tc.expr)
+		if !ok || got != tc.want {
+			t.Errorf("posixRegexp(%q) = %q, %t, want %q, true", tc.expr, got, ok, tc.want)
+			continue
+		}
+		re, posix := regexp.MustCompile(tc.expr), regexp.MustCompilePOSIX(got)
+		for _, sample := range samples {
+			if re.MatchString(sample) != posix.MatchString(sample) {
+				t.Errorf("posixRegexp(%q) = %q, which differs on %q", tc.expr, got, sample)
+			}
+		}
+	}
+
+	for _, expr := range []string{`(?i)^ads\.example\.com$`, `\bads\.`, `^a(|b)$`, `[[:alpha:]x\[]`} {
+		if got, ok := posixRegexp(expr); ok {
+			t.Errorf("posixRegexp(%q) = %q, want failure", expr, got)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+var listReport = flag.String("listreport", "", "Name of the report file classifying all lists by their sources and usages (empty to skip)")
+
+// Classes of lists in the report
+const (
+	ListFileBacked      string = "file-backed"      // The list has a data file
+	ListAffiliationOnly string = "affiliation-only" // The list only exists by affiliations
+	ListIncluded        string = "included"         // The list is included by other lists
+	ListLeaf            string = "leaf"             // No list includes the list
+)
+
+type ListInfo struct {
+	Name       string
+	Source     string // ListFileBacked or ListAffiliationOnly
+	Usage      string // ListIncluded or ListLeaf
+	IncludedBy []string
+	RulesCount int
+	Notes      []string
+}
+
+// classifyLists classifies every list by its source and usage, and notes the
+// likely typos of affiliation targets and the lists which end up empty.
+func (p *Processor) classifyLists() []*ListInfo {
+	includedBy := make(map[string][]string)
+	fileNames := make([]string, 0, len(p.parsedListByName))
+	for plname, pl := range p.parsedListByName {
+		for _, inc := range pl.Inclusions {
+			if !slices.Contains(includedBy[inc.Source], plname) {
+				includedBy[inc.Source] = append(includedBy[inc.Source], plname)
+			}
+		}
+		if pl.HasFile {
+			fileNames = append(fileNames, plname)
+		}
+	}
+	slices.Sort(fileNames)
+
+	infos := make([]*ListInfo, 0, len(p.parsedListByName))
+	for plname, pl := range p.parsedListByName {
+		info := &ListInfo{
+			Name:       plname,
+			Source:     ListFileBacked,
+			Usage:      ListLeaf,
+			IncludedBy: includedBy[plname],
+			RulesCount: len(pl.FinalEntries),
+		}
+		slices.Sort(info.IncludedBy)
+		if len(info.IncludedBy) != 0 {
+			info.Usage = ListIncluded
+		}
+		if !pl.HasFile {
+			info.Source = ListAffiliationOnly
+			affiliators := slices.Sorted(slices.Values(pl.Affiliators))
+			info.Notes = append(info.Notes, "affiliated by "+strings.Join(affiliators, ","))
+			if similar := similarNames(plname, fileNames); len(similar) != 0 {
+				info.Notes = append(info.Notes, "likely typo of "+strings.Join(similar, ","))
+			}
+		}
+		if info.RulesCount == 0 {
+			info.Notes = append(info.Notes, "empty after filtering")
+		}
+		infos = append(infos, info)
+	}
+	slices.SortFunc(infos, func(a, b *ListInfo) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return infos
+}
+
+// similarNames returns the candidates within a small edit distance of name.
+func similarNames(name string, candidates []string) []string {
+	var similar []string
+	for _, candidate := range candidates {
+		// Allow one edit per four characters, and at most two edits
+		if d := editDistance(name, candidate); d > 0 && d <= 2 && 4*d <= len(name) {
+			similar = append(similar, candidate)
+		}
+	}
+	return similar
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// writeListReport writes the classes of the lists as tab-separated lines,
+// after a summary of the counts of the classes.
+func writeListReport(w io.Writer, infos []*ListInfo) error {
+	counts := make(map[string]int)
+	for _, info := range infos {
+		counts[info.Source]++
+		counts[info.Usage]++
+	}
+	fmt.Fprintf(w, "# %d lists: %d %s, %d %s, %d %s, %d %s\n", len(infos),
+		counts[ListFileBacked], ListFileBacked, counts[ListAffiliationOnly], ListAffiliationOnly,
+		counts[ListIncluded], ListIncluded, counts[ListLeaf], ListLeaf)
+	fmt.Fprintln(w, "# name\tsource\tusage\trules\tnotes")
+	for _, info := range infos {
+		usage := info.Usage
+		if len(info.IncludedBy) != 0 {
+			usage += "(" + strings.ToLower(strings.Join(info.IncludedBy, ",")) + ")"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", strings.ToLower(info.Name), info.Source, usage,
+			info.RulesCount, strings.ToLower(strings.Join(info.Notes, "; ")))
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestClassifyLists(t *testing.T) {
+	dataPath := t.TempDir()
+	files := map[string]string{
+		"category-media": "include:youtube @ads\n",
+		"streaming":      "include:netflix\n",
+		"netflix":        "domain:netflix.com &category-meida\n",
+		"youtube":        "domain:youtube.com &video-sites\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dataPath, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write test data %q: %v", name, err)
+		}
+	}
+	processor := &Processor{parsedListByName: make(map[string]*ParsedList)}
+	if err := processor.loadDataDir(dataPath); err != nil {
+		t.Fatalf("loadDataDir got unexpected error: %v", err)
+	}
+	if err := processor.resolveAll(); err != nil {
+		t.Fatalf("resolveAll got unexpected error: %v", err)
+	}
+
+	want := map[string]struct {
+		source, usage string
+		notes         []string
+	}{
+		"CATEGORY-MEDIA": {ListFileBacked, ListLeaf, []string{"empty after filtering"}},
+		"CATEGORY-MEIDA": {ListAffiliationOnly, ListLeaf, []string{"affiliated by NETFLIX", "likely typo of CATEGORY-MEDIA"}},
+		"NETFLIX":        {ListFileBacked, ListIncluded, nil},
+		"STREAMING":      {ListFileBacked, ListLeaf, nil},
+		"VIDEO-SITES":    {ListAffiliationOnly, ListLeaf, []string{"affiliated by YOUTUBE"}},
+		"YOUTUBE":        {ListFileBacked, ListIncluded, nil},
+	}
+	infos := processor.classifyLists()
+	if len(infos) != len(want) {
+		t.Fatalf("classifyLists() returned %d lists, want %d", len(infos), len(want))
+	}
+	for _, info := range infos {
+		w, ok := want[info.Name]
+		if !ok {
+			t.Errorf("classifyLists() returned unexpected list %q", info.Name)
+			continue
+		}
+		if info.Source != w.source || info.Usage != w.usage || !slices.Equal(info.Notes, w.notes) {
+			t.Errorf("classifyLists() %q = %s/%s %v, want %s/%s %v", info.Name, info.Source, info.Usage, info.Notes, w.source, w.usage, w.notes)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := writeListReport(&buf, infos); err != nil {
+		t.Fatalf("writeListReport got unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if want := "# 6 lists: 4 file-backed, 2 affiliation-only, 2 included, 4 leaf"; lines[0] != want {
+		t.Errorf("writeListReport() summary = %q, want %q", lines[0], want)
+	}
+	if want := "netflix\tfile-backed\tincluded(streaming)\t1\t"; !slices.Contains(lines, want) {
+		t.Errorf("writeListReport() = %q, want line %q", lines, want)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	testCases := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"CN", "", 2},
+		{"GOOGLE", "GOOGLE", 0},
+		{"CATEGORY-MEIDA", "CATEGORY-MEDIA", 2},
+		{"GITHUB", "GITLAB", 2},
+		{"KITTEN", "SITTING", 3},
+	}
+	for _, tc := range testCases {
+		if got := editDistance(tc.a, tc.b); got != tc.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+	if got := similarNames("CN", []string{"JP", "CN"}); len(got) != 0 {
+		t.Errorf("similarNames(%q) = %v, want none for short names", "CN", strings.Join(got, ","))
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/netip"
+	"strings"
+
+	"github.com/v2fly/domain-list-community/internal/dlc"
+)
+
+// Actions of Response Policy Zone rules, besides redirecting to an address
+// with local data
+const (
+	RPZActionNXDomain string = "nxdomain"
+	RPZActionNoData   string = "nodata"
+	RPZActionPassthru string = "passthru"
+	RPZActionDrop     string = "drop"
+)
+
+// rpzRecord returns the record of the RPZ action, e.g. `CNAME .` for
+// nxdomain, or `A 0.0.0.0` for the local data of the address `0.0.0.0`.
+func rpzRecord(action string) (string, error) {
+	switch strings.ToLower(action) {
+	case RPZActionNXDomain:
+		return "CNAME .", nil
+	case RPZActionNoData:
+		return "CNAME *.", nil
+	case RPZActionPassthru:
+		return "CNAME rpz-passthru.", nil
+	case RPZActionDrop:
+		return "CNAME rpz-drop.", nil
+	}
+	addr, err := netip.ParseAddr(action)
+	if err != nil || addr.Zone() != "" {
+		return "", fmt.Errorf("invalid RPZ action: %q", action)
+	}
+	if addr.Is4() {
+		return "A " + addr.String(), nil
+	}
+	return "AAAA " + addr.String(), nil
+}
+
+// writeRPZ writes the lists as a Response Policy Zone file with the `action`
+// of each list, where a domain type rule becomes the domain and the wildcard
+// of its subdomains, and a full type rule becomes the exact name. The owner
+// names are relative to the origin of the zone, and the serial is the build
+// time.
+func writeRPZ(w io.Writer, lists []*ExportList, opts ExportOptions) error {
+	built, err := buildTime()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "$TTL 300\n@ SOA localhost. hostmaster.localhost. %d 3600 600 86400 300\n@ NS localhost.\n",
+		uint32(built.Unix()))
+	seen := make(map[string]bool)
+	for _, list := range lists {
+		record, err := rpzRecord(opts.getFor("action", list.Name, RPZActionNXDomain))
+		if err != nil {
+			return fmt.Errorf("failed to get action of list %q: %w", list.Name, err)
+		}
+		var dropped []*Entry
+		for _, entry := range stripAttrs(list.Entries) {
+			var names []string
+			switch entry.Type {
+			case dlc.RuleTypeDomain:
+				names = []string{entry.Value, "*." + entry.Value}
+			case dlc.RuleTypeFullDomain:
+				names = []string{entry.Value}
+			default:
+				dropped = append(dropped, entry)
+				continue
+			}
+			for _, name := range names {
+				if !seen[name] { // The first list of a name wins
+					seen[name] = true
+					fmt.Fprintf(w, "%s %s\n", name, record)
+				}
+			}
+		}
+		warnDropped("rpz", []*ExportList{list}, dropped)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteRPZ(t *testing.T) {
+	lists := []*ExportList{
+		testExportList(t, "category-ads",
+			"domain:example.com",
+			"full:ads.example.org",
+			`regexp:^ads\d\.example\.net$`, // Dropped
+		),
+		testExportList(t, "private",
+			"domain:example.com", // Duplicated
+			"full:router.example.net",
+		),
+		testExportList(t, "direct", "domain:example.cn"),
+	}
+	opts := ExportOptions{"action:private": "192.168.1.1", "action:direct": "Passthru"}
+	t.Setenv("SOURCE_DATE_EPOCH", "1767323045")
+	var buf bytes.Buffer
+	if err := writeRPZ(&buf, lists, opts); err != nil {
+		t.Fatalf("writeRPZ got unexpected error: %v", err)
+	}
+	want := `$TTL 300
+@ SOA localhost. hostmaster.localhost. 1767323045 3600 600 86400 300
+@ NS localhost.
+example.com CNAME .
+*.example.com CNAME .
+ads.example.org CNAME .
+router.example.net A 192.168.1.1
+example.cn CNAME rpz-passthru.
+*.example.cn CNAME rpz-passthru.
+`
+	if got := buf.String(); got != want {
+		t.Errorf("writeRPZ() = %s, want %s", got, want)
+	}
+
+	for _, action := range []string{"nodata", "drop", "::1"} {
+		if _, err := rpzRecord(action); err != nil {
+			t.Errorf("rpzRecord(%q) got unexpected error: %v", action, err)
+		}
+	}
+	if err := writeRPZ(new(bytes.Buffer), lists, ExportOptions{"action": "reject"}); err == nil {
+		t.Error("writeRPZ() with action \"reject\" = nil error, want invalid action error")
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+
+	"github.com/v2fly/domain-list-community/internal/dlc"
+)
+
+// Versions of sing-box rule-sets
+const (
+	SingboxVersion1 uint8 = 1 + iota // sing-box 1.8.0+
+	SingboxVersion2                  // sing-box 1.10.0+, with the new domain suffix matcher
+	SingboxVersion3                  // sing-box 1.11.0+
+)
+
+// Items of the sing-box binary rule-set format
+const (
+	srsItemDomain        uint8 = 2
+	srsItemDomainKeyword uint8 = 3
+	srsItemDomainRegex   uint8 = 4
+	srsItemFinal         uint8 = 0xFF
+
+	srsPrefixLabel byte = '\r' // Matches subdomains after the label
+	srsRootLabel   byte = '\n' // Matches the domain and its subdomains after the label
+)
+
+const srsMagic = "SRS"
+
+type singboxRuleSet struct {
+	Version uint8          `json:"version"`
+	Rules   []*singboxRule `json:"rules"`
+}
+
+type singboxRule struct {
+	Domain        []string `json:"domain,omitempty"`
+	DomainSuffix  []string `json:"domain_suffix,omitempty"`
+	DomainKeyword []string `json:"domain_keyword,omitempty"`
+	DomainRegex   []string `json:"domain_regex,omitempty"`
+}
+
+// newSingboxRule returns the headless rule matching any of the entries, as
+// items of a sing-box default rule are ORed within the domain items.
+func newSingboxRule(entries []*Entry) *singboxRule {
+	rule := new(singboxRule)
+	for _, entry := range stripAttrs(entries) {
+		switch entry.Type {
+		case dlc.RuleTypeFullDomain:
+			rule.Domain = append(rule.Domain, entry.Value)
+		case dlc.RuleTypeDomain:
+			rule.DomainSuffix = append(rule.DomainSuffix, entry.Value)
+		case dlc.RuleTypeKeyword:
+			rule.DomainKeyword = append(rule.DomainKeyword, entry.Value)
+		case dlc.RuleTypeRegexp:
+			rule.DomainRegex = append(rule.DomainRegex, entry.Value)
+		}
+	}
+	return rule
+}
+
+func singboxVersion(opts ExportOptions) (uint8, error) {
+	rawVersion := opts.get("version", strconv.Itoa(int(SingboxVersion2)))
+	version, err := strconv.ParseUint(rawVersion, 10, 8)
+	if err != nil || uint8(version) < SingboxVersion1 || uint8(version) > SingboxVersion3 {
+		return 0, fmt.Errorf("invalid sing-box rule-set version: %q", rawVersion)
+	}
+	return uint8(version), nil
+}
+
+// writeSingboxSource writes the lists as a sing-box rule-set source file.
+func writeSingboxSource(w io.Writer, lists []*ExportList, opts ExportOptions) error {
+	version, err := singboxVersion(opts)
+	if err != nil {
+		return err
+	}
+	ruleSet := &singboxRuleSet{Version: version, Rules: []*singboxRule{newSingboxRule(mergeEntries(lists))}}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false) // Keep '<', '>' and '&' of regexps readable
+	enc.SetIndent("", "  ")
+	return enc.Encode(ruleSet)
+}
+
+// writeSingboxBinary writes the lists as a sing-box binary rule-set, which is
+// what `sing-box rule-set compile` outputs for the source file.
+func writeSingboxBinary(w io.Writer, lists []*ExportList, opts ExportOptions) error {
+	version, err := singboxVersion(opts)
+	if err != nil {
+		return err
+	}
+	rule := newSingboxRule(mergeEntries(lists))
+	if _, err := w.Write(append([]byte(srsMagic), version)); err != nil {
+		return err
+	}
+	b := binary.AppendUvarint(nil, 1) // Number of rules
+	b = append(b, 0)                  // Default rule
+	if len(rule.Domain) != 0 || len(rule.DomainSuffix) != 0 {
+		keys := srsDomainKeys(rule.Domain, rule.DomainSuffix, version == SingboxVersion1)
+		b = newSuccinctSet(keys).appendSrs(append(b, srsItemDomain))
+	}
+	if len(rule.DomainKeyword) != 0 {
+		b = appendSrsStrings(b, srsItemDomainKeyword, rule.DomainKeyword)
+	}
+	if len(rule.DomainRegex) != 0 {
+		b = appendSrsStrings(b, srsItemDomainRegex, rule.DomainRegex)
+	}
+	b = append(b, srsItemFinal, 0) // Not inverted
+
+	zw, err := zlib.NewWriterLevel(w, zlib.BestCompression)
+	if err != nil {
+		return err
+	}
+	if _, err := zw.Write(b); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// srsDomainKeys returns the sorted keys of the domain matcher. A domain suffix
+// is a single key starting with the root label since version 2, while legacy
+// matchers need the domain itself and the suffix with the prefix label.
+func srsDomainKeys(domains, suffixes []string, legacy bool) []string {
+	keys := make([]string, 0, len(domains)+2*len(suffixes))
+	for _, suffix := range suffixes {
+		if legacy {
+			keys = append(keys, reverseDomain(suffix), reverseDomain(string(srsPrefixLabel)+"."+suffix))
+		} else {
+			keys = append(keys, reverseDomain(string(srsRootLabel)+suffix))
+		}
+	}
+	for _, domain := range domains {
+		keys = append(keys, reverseDomain(domain))
+	}
+	slices.Sort(keys)
+	return slices.Compact(keys)
+}
+
+// appendSrs appends the set encoded as a sing-box domain matcher.
+func (ss *succinctSet) appendSrs(b []byte) []byte {
+	b = append(b, 0) // Reserved
+	b = binary.AppendUvarint(b, uint64(len(ss.leaves)))
+	for _, word := range ss.leaves {
+		b = binary.BigEndian.AppendUint64(b, word)
+	}
+	b = binary.AppendUvarint(b, uint64(len(ss.labelBitmap)))
+	for _, word := range ss.labelBitmap {
+		b = binary.BigEndian.AppendUint64(b, word)
+	}
+	b = binary.AppendUvarint(b, uint64(len(ss.labels)))
+	return append(b, ss.labels...)
+}
+
+func appendSrsStrings(b []byte, item uint8, values []string) []byte {
+	b = append(b, item)
+	b = binary.AppendUvarint(b, uint64(len(values)))
+	for _, value := range values {
+		b = binary.AppendUvarint(b, uint64(len(value)))
+		b = append(b, value...)
+	}
+	return b
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func testSingboxLists(t *testing.T) []*ExportList {
+	return []*ExportList{
+		testExportList(t, "first",
+			"domain:example.com @ads",
+			"full:www.example.com",
+			"full:example.org",
+			"keyword:tracker",
+		),
+		testExportList(t, "second",
+			"domain:example.com",
+			"domain:example.net @cn",
+			`regexp:^ads\d+\.example\.edu$`,
+			"regexp:^a<b>&c$",
+		),
+	}
+}
+
+func TestWriteSingboxSource(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeSingboxSource(&buf, testSingboxLists(t), ExportOptions{"version": "3"}); err != nil {
+		t.Fatalf("writeSingboxSource got unexpected error: %v", err)
+	}
+	want := `{
+  "version": 3,
+  "rules": [
+    {
+      "domain": [
+        "example.org"
+      ],
+      "domain_suffix": [
+        "example.com",
+        "example.net"
+      ],
+      "domain_keyword": [
+        "tracker"
+      ],
+      "domain_regex": [
+        "^a<b>&c$",
+        "^ads\\d+\\.example\\.edu$"
+      ]
+    }
+  ]
+}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("writeSingboxSource() = %s, want %s", got, want)
+	}
+
+	if err := writeSingboxSource(&buf, testSingboxLists(t), ExportOptions{"version": "4"}); err == nil {
+		t.Error("writeSingboxSource() with version 4 = nil error, want invalid version error")
+	}
+}
+
+// TestWriteSingboxBinary decodes the binary rule-sets of every version back
+// into the source rule.
+func TestWriteSingboxBinary(t *testing.T) {
+	lists := testSingboxLists(t)
+	want := newSingboxRule(mergeEntries(lists))
+	for _, version := range []uint8{SingboxVersion1, SingboxVersion2, SingboxVersion3} {
+		var buf bytes.Buffer
+		opts := ExportOptions{"version": fmt.Sprint(version)}
+		if err := writeSingboxBinary(&buf, lists, opts); err != nil {
+			t.Fatalf("writeSingboxBinary(version %d) got unexpected error: %v", version, err)
+		}
+		got, gotVersion, err := readSingboxBinary(&buf)
+		if err != nil {
+			t.Fatalf("readSingboxBinary(version %d) got unexpected error: %v", version, err)
+		}
+		if gotVersion != version {
+			t.Errorf("readSingboxBinary() version = %d, want %d", gotVersion, version)
+		}
+		for _, field := range []struct {
+			name      string
+			got, want []string
+		}{
+			{"domain", got.Domain, want.Domain},
+			{"domain_suffix", got.DomainSuffix, want.DomainSuffix},
+			{"domain_keyword", got.DomainKeyword, want.DomainKeyword},
+			{"domain_regex", got.DomainRegex, want.DomainRegex},
+		} {
+			if !slices.Equal(field.got, field.want) {
+				t.Errorf("version %d %s = %q, want %q", version, field.name, field.got, field.want)
+			}
+		}
+	}
+}
+
+// readSingboxBinary decodes a binary rule-set written by writeSingboxBinary.
+func readSingboxBinary(r io.Reader) (*singboxRule, uint8, error) {
+	header := make([]byte, len(srsMagic)+1)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, 0, err
+	}
+	if string(header[:len(srsMagic)]) != srsMagic {
+		return nil, 0, fmt.Errorf("invalid magic %q", header[:len(srsMagic)])
+	}
+	version := header[len(srsMagic)]
+	zr, err := zlib.NewReader(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	br := bufio.NewReader(zr)
+	if count, err := binary.ReadUvarint(br); err != nil || count != 1 {
+		return nil, 0, fmt.Errorf("got %d rules, want 1: %v", count, err)
+	}
+	if typ, err := br.ReadByte(); err != nil || typ != 0 {
+		return nil, 0, fmt.Errorf("got rule type %d, want default rule: %v", typ, err)
+	}
+	rule := new(singboxRule)
+	for {
+		item, err := br.ReadByte()
+		if err != nil {
+			return nil, 0, err
+		}
+		switch item {
+		case srsItemDomain:
+			keys, err := readSrsSet(br)
+			if err != nil {
+				return nil, 0, err
+			}
+			rule.Domain, rule.DomainSuffix = srsDomainsOfKeys(keys)
+		case srsItemDomainKeyword:
+			if rule.DomainKeyword, err = readSrsStrings(br); err != nil {
+				return nil, 0, err
+			}
+		case srsItemDomainRegex:
+			if rule.DomainRegex, err = readSrsStrings(br); err != nil {
+				return nil, 0, err
+			}
+		case srsItemFinal:
+			if invert, err := br.ReadByte(); err != nil || invert != 0 {
+				return nil, 0, fmt.Errorf("got invert %d, want 0: %v", invert, err)
+			}
+			if _, err := br.ReadByte(); err != io.EOF {
+				return nil, 0, fmt.Errorf("got trailing data, want EOF: %v", err)
+			}
+			return rule, version, nil
+		default:
+			return nil, 0, fmt.Errorf("unexpected item %d", item)
+		}
+	}
+}
+
+// readSrsSet decodes the keys of a sing-box domain matcher.
+func readSrsSet(br *bufio.Reader) ([]string, error) {
+	if _, err := br.ReadByte(); err != nil { // Reserved
+		return nil, err
+	}
+	readWords := func() ([]uint64, error) {
+		n, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, err
+		}
+		words := make([]uint64, n)
+		return words, binary.Read(br, binary.BigEndian, words)
+	}
+	leaves, err := readWords()
+	if err != nil {
+		return nil, err
+	}
+	labelBitmap, err := readWords()
+	if err != nil {
+		return nil, err
+	}
+	n, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, err
+	}
+	labels := make([]byte, n)
+	if _, err := io.ReadFull(br, labels); err != nil {
+		return nil, err
+	}
+	return succinctKeys(leaves, labelBitmap, labels), nil
+}
+
+// srsDomainsOfKeys restores the domains and domain suffixes of the matcher
+// keys of any version.
+func srsDomainsOfKeys(keys []string) (domains, suffixes []string) {
+	exact := make(map[string]bool)
+	for _, key := range keys {
+		domain := reverseDomain(key)
+		if suffix, ok := strings.CutPrefix(domain, string(srsRootLabel)); ok {
+			suffixes = append(suffixes, suffix)
+		} else if suffix, ok := strings.CutPrefix(domain, string(srsPrefixLabel)+"."); ok {
+			suffixes = append(suffixes, suffix)
+		} else {
+			exact[domain] = true
+		}
+	}
+	for _, suffix := range suffixes {
+		delete(exact, suffix) // The domain itself in legacy matchers
+	}
+	for domain := range exact {
+		domains = append(domains, domain)
+	}
+	slices.Sort(domains)
+	slices.Sort(suffixes)
+	return domains, suffixes
+}
+
+func readSrsStrings(br *bufio.Reader) ([]string, error) {
+	n, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]string, n)
+	for i := range values {
+		size, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, err
+		}
+		value := make([]byte, size)
+		if _, err := io.ReadFull(br, value); err != nil {
+			return nil, err
+		}
+		values[i] = string(value)
+	}
+	return values, nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// writeSmartDNSSet writes the lists as a SmartDNS domain-set file, which is
+// loaded by `domain-set -name <name> -file <file>`, and where every domain
+// matches its subdomains.
+func writeSmartDNSSet(w io.Writer, lists []*ExportList, _ ExportOptions) error {
+	for _, domain := range suffixDomains("smartdns-set", lists, false) {
+		fmt.Fprintln(w, domain)
+	}
+	return nil
+}
+
+// writeSmartDNS writes the lists as SmartDNS rules like
+// `nameserver /example.com/group`, with the server group of the `group`
+// option of each list. The first list of a domain wins.
+func writeSmartDNS(w io.Writer, lists []*ExportList, opts ExportOptions) error {
+	seen := make(map[string]bool)
+	for _, list := range lists {
+		group := opts.getFor("group", list.Name, "")
+		if group == "" || strings.ContainsAny(group, "/ \t\r\n") {
+			return fmt.Errorf("invalid group %q of list %q", group, list.Name)
+		}
+		for _, domain := range suffixDomains("smartdns", []*ExportList{list}, false) {
+			if !seen[domain] {
+				seen[domain] = true
+				fmt.Fprintf(w, "nameserver /%s/%s\n", domain, group)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteSmartDNS(t *testing.T) {
+	lists := []*ExportList{
+		testExportList(t, "cn",
+			"domain:example.cn",
+			"full:www.example.com", // Approximated
+			"keyword:baidu",        // Dropped
+		),
+		testExportList(t, "gfw",
+			"domain:example.cn", // Duplicated
+			"domain:example.org",
+		),
+	}
+	var buf bytes.Buffer
+	if err := writeSmartDNSSet(&buf, lists, nil); err != nil {
+		t.Fatalf("writeSmartDNSSet got unexpected error: %v", err)
+	}
+	if got, want := buf.String(), "example.cn\nexample.org\nwww.example.com\n"; got != want {
+		t.Errorf("writeSmartDNSSet() = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	if err := writeSmartDNS(&buf, lists, ExportOptions{"group": "foreign", "group:cn": "domestic"}); err != nil {
+		t.Fatalf("writeSmartDNS got unexpected error: %v", err)
+	}
+	want := "nameserver /example.cn/domestic\n" +
+		"nameserver /www.example.com/domestic\n" +
+		"nameserver /example.org/foreign\n"
+	if got := buf.String(); got != want {
+		t.Errorf("writeSmartDNS() = %q, want %q", got, want)
+	}
+
+	if err := writeSmartDNS(&buf, lists, ExportOptions{"group": "a/b"}); err == nil {
+		t.Error("writeSmartDNS() with group \"a/b\" = nil error, want invalid group error")
+	}
+}
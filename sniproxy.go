@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/v2fly/domain-list-community/internal/dlc"
+)
+
+// writeNginxMap writes the lists as the entries of an nginx `map` block with
+// the `hostnames` parameter, like the map of `$ssl_preread_server_name` for
+// routing by SNI, where every domain maps to the `target` of its list.
+// Regexps are tried in order after the domains, and the first list of a rule
+// wins.
+func writeNginxMap(w io.Writer, lists []*ExportList, opts ExportOptions) error {
+	seen := make(map[string]bool)
+	for _, list := range lists {
+		target := opts.getFor("target", list.Name, "")
+		if target == "" || strings.ContainsAny(target, "\"\\\r\n") {
+			return fmt.Errorf("invalid target %q of list %q", target, list.Name)
+		}
+		var dropped []*Entry
+		for _, entry := range stripAttrs(list.Entries) {
+			var key string
+			switch entry.Type {
+			case dlc.RuleTypeDomain:
+				key = "." + entry.Value
+			case dlc.RuleTypeFullDomain:
+				key = entry.Value
+			case dlc.RuleTypeKeyword:
+				key = `"~` + regexp.QuoteMeta(entry.Value) + `"`
+			case dlc.RuleTypeRegexp:
+				// Backslashes escape quotes and themselves in quoted strings
+				if strings.ContainsAny(entry.Value, `"`) || strings.Contains(entry.Value, `\\`) {
+					dropped = append(dropped, entry)
+					continue
+				}
+				key = `"~` + entry.Value + `"`
+			}
+			if !seen[key] {
+				seen[key] = true
+				fmt.Fprintf(w, "%s \"%s\";\n", key, target)
+			}
+		}
+		warnDropped("nginx", []*ExportList{list}, dropped)
+	}
+	return nil
+}
+
+// writeHAProxyMap writes the lists as an HAProxy map file of regexps for
+// `map_reg`, like `use_backend %[req.ssl_sni,lower,map_reg(file)]`, where
+// every rule maps to the `target` of its list. The first list of a rule wins.
+func writeHAProxyMap(w io.Writer, lists []*ExportList, opts ExportOptions) error {
+	seen := make(map[string]bool)
+	for _, list := range lists {
+		target := opts.getFor("target", list.Name, "")
+		if strings.TrimSpace(target) == "" || strings.ContainsAny(target, "\r\n") {
+			return fmt.Errorf("invalid target %q of list %q", target, list.Name)
+		}
+		for _, entry := range stripAttrs(list.Entries) {
+			var key string
+			switch entry.Type {
+			case dlc.RuleTypeDomain:
+				key = `^(.+\.)?` + regexp.QuoteMeta(entry.Value) + `$`
+			case dlc.RuleTypeFullDomain:
+				key = `^` + regexp.QuoteMeta(entry.Value) + `$`
+			case dlc.RuleTypeKeyword:
+				key = regexp.QuoteMeta(entry.Value)
+			case dlc.RuleTypeRegexp:
+				key = entry.Value // Never with spaces or '#', which are invalid in rules
+			}
+			if !seen[key] {
+				seen[key] = true
+				fmt.Fprintf(w, "%s %s\n", key, target)
+			}
+		}
+	}
+	return nil
+}
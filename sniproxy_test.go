@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func testSNIProxyLists(t *testing.T) []*ExportList {
+	return []*ExportList{
+		testExportList(t, "cn",
+			"domain:example.cn",
+			"full:www.example.com",
+			"keyword:baidu",
+		),
+		testExportList(t, "gfw",
+			"domain:example.com",
+			"domain:example.cn", // Duplicated
+			`regexp:^ads\d+\.example\.net$`,
+			`regexp:^a"b$`, // Dropped by nginx
+		),
+	}
+}
+
+func TestWriteNginxMap(t *testing.T) {
+	var buf bytes.Buffer
+	opts := ExportOptions{"target": "proxy:443", "target:cn": "$ssl_preread_server_name:443"}
+	if err := writeNginxMap(&buf, testSNIProxyLists(t), opts); err != nil {
+		t.Fatalf("writeNginxMap got unexpected error: %v", err)
+	}
+	want := `.example.cn "$ssl_preread_server_name:443";
+www.example.com "$ssl_preread_server_name:443";
+"~baidu" "$ssl_preread_server_name:443";
+.example.com "proxy:443";
+"~^ads\d+\.example\.net$" "proxy:443";
+`
+	if got := buf.String(); got != want {
+		t.Errorf("writeNginxMap() = %s, want %s", got, want)
+	}
+
+	if err := writeNginxMap(&buf, testSNIProxyLists(t), ExportOptions{"target:cn": "a"}); err == nil {
+		t.Error("writeNginxMap() without target of gfw = nil error, want error")
+	}
+}
+
+func TestWriteHAProxyMap(t *testing.T) {
+	var buf bytes.Buffer
+	opts := ExportOptions{"target": "be_proxy", "target:cn": "be_direct"}
+	if err := writeHAProxyMap(&buf, testSNIProxyLists(t), opts); err != nil {
+		t.Fatalf("writeHAProxyMap got unexpected error: %v", err)
+	}
+	want := `^(.+\.)?example\.cn$ be_direct
+^www\.example\.com$ be_direct
+baidu be_direct
+^(.+\.)?example\.com$ be_proxy
+^a"b$ be_proxy
+^ads\d+\.example\.net$ be_proxy
+`
+	if got := buf.String(); got != want {
+		t.Errorf("writeHAProxyMap() = %s, want %s", got, want)
+	}
+
+	if err := writeHAProxyMap(&buf, testSNIProxyLists(t), ExportOptions{"target": " "}); err == nil {
+		t.Error("writeHAProxyMap() with blank target = nil error, want error")
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/v2fly/domain-list-community/internal/manifest"
+)
+
+// splitIndexName is the name of the index file in the directory of a split
+// task.
+const splitIndexName = "index.json"
+
+type SplitFile struct {
+	Size   int64    `json:"size"`
+	SHA256 string   `json:"sha256"`
+	Lists  []string `json:"lists"`
+}
+
+// SplitIndex maps the lists of a split task to their dat files, and the dat
+// files to their sizes, SHA-256 digests and lists.
+type SplitIndex struct {
+	Lists map[string]string     `json:"lists"`
+	Files map[string]*SplitFile `json:"files"`
+}
+
+// splitGroups returns the names of the dat files and the indexes of their
+// sites, which are the sites of the named groups of the task, and every other
+// site alone.
+func (gs *GeoSites) splitGroups(task DatTask, idxes []int) (map[string][]int, error) {
+	groups := make(map[string][]int)
+	grouped := make(map[int]string)
+	for group, lists := range task.Groups {
+		name := strings.ToLower(group)
+		for _, list := range lists {
+			idx, ok := gs.SiteIdx[strings.ToUpper(list)]
+			if !ok || !slices.Contains(idxes, idx) {
+				return nil, fmt.Errorf("list %q of group %q is not selected by the task", list, group)
+			}
+			if other, ok := grouped[idx]; ok && other != name {
+				return nil, fmt.Errorf("list %q is in both groups %q and %q", list, other, name)
+			}
+			grouped[idx] = name
+			groups[name] = append(groups[name], idx)
+		}
+	}
+	for _, idx := range idxes {
+		if _, ok := grouped[idx]; !ok {
+			name := strings.ToLower(gs.Sites[idx].CountryCode)
+			if _, ok := groups[name]; ok {
+				return nil, fmt.Errorf("group %q has the same name as list %q", name, name)
+			}
+			groups[name] = []int{idx}
+		}
+	}
+	for name, group := range groups {
+		slices.Sort(group)
+		groups[name] = slices.Compact(group)
+	}
+	return groups, nil
+}
+
+// removeStaleSplits removes the dat files of the groups and lists of former
+// runs from the directory, with their compressed variants and checksum
+// sidecars, which the index no longer lists. Names of groups and lists have no
+// dot, so `<name>.dat` and `<name>.dat.*` are all the files of a name.
+func removeStaleSplits(dirName string, index *SplitIndex) error {
+	entries, err := os.ReadDir(filepath.Join(*outputDir, dirName))
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name, ext, _ := strings.Cut(entry.Name(), ".")
+		if entry.IsDir() || (ext != "dat" && !strings.HasPrefix(ext, "dat.")) {
+			continue
+		}
+		if _, ok := index.Files[name+".dat"]; !ok {
+			if err := os.Remove(filepath.Join(*outputDir, dirName, entry.Name())); err != nil {
+				return fmt.Errorf("failed to remove stale file %q: %w", entry.Name(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// assembleSplit writes the sites selected by the task into the directory named
+// by the task, one dat file per site or per named group, and the index file of
+// them, so that clients may load only the lists they need. The dat files of
+// former runs which are not in the index are removed.
+func (gs *GeoSites) assembleSplit(task DatTask) error {
+	dirName := strings.ToLower(filepath.Base(task.Name))
+	idxes, err := gs.selectSites(task)
+	if err != nil {
+		return err
+	}
+	groups, err := gs.splitGroups(task, idxes)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(*outputDir, dirName), 0755); err != nil {
+		return fmt.Errorf("failed to create directory %q: %w", dirName, err)
+	}
+
+	index := &SplitIndex{Lists: make(map[string]string), Files: make(map[string]*SplitFile)}
+	for name, group := range groups {
+		datFileName := name + ".dat"
+		filename := filepath.Join(dirName, datFileName)
+		if err := writeOutput(filename, func(w io.Writer) error {
+			for _, idx := range group {
+				if _, err := w.Write(gs.Records[idx]); err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+			return fmt.Errorf("failed to write file %q: %w", filename, err)
+		}
+		if err := addOutput(filepath.ToSlash(filename), FormatSplit, len(group), gs.countRules(group)); err != nil {
+			return err
+		}
+		file := &SplitFile{Lists: make([]string, len(group))}
+		if file.Size, file.SHA256, err = manifest.HashFile(filepath.Join(*outputDir, filename)); err != nil {
+			return fmt.Errorf("failed to hash %q: %w", filename, err)
+		}
+		for i, idx := range group {
+			file.Lists[i] = strings.ToLower(gs.Sites[idx].CountryCode)
+			index.Lists[file.Lists[i]] = datFileName
+		}
+		index.Files[datFileName] = file
+	}
+
+	filename := filepath.Join(dirName, splitIndexName)
+	if err := writeOutput(filename, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return enc.Encode(index)
+	}); err != nil {
+		return fmt.Errorf("failed to write file %q: %w", filename, err)
+	}
+	if err := addOutput(filepath.ToSlash(filename), "split-index", len(idxes), nil); err != nil {
+		return err
+	}
+	if err := removeStaleSplits(dirName, index); err != nil {
+		return err
+	}
+	fmt.Printf("split dats %q of %d file(s) have been generated successfully\n", dirName, len(groups))
+	return nil
+}
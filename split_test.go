@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	router "github.com/v2fly/v2ray-core/v5/app/router/routercommon"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestAssembleSplit(t *testing.T) {
+	var sites []*router.GeoSite
+	for _, name := range []string{"APPLE", "CN", "GOOGLE", "ICLOUD"} {
+		sites = append(sites, &router.GeoSite{
+			CountryCode: name,
+			Domain:      []*router.Domain{{Type: router.Domain_RootDomain, Value: "example.com"}},
+		})
+	}
+	gs, err := newGeoSites(sites)
+	if err != nil {
+		t.Fatalf("newGeoSites got unexpected error: %v", err)
+	}
+
+	defer func(dir string) { *outputDir = dir }(*outputDir)
+	*outputDir = t.TempDir()
+	task := DatTask{
+		Name:   "Geosite",
+		Mode:   ModeDenylist,
+		Lists:  []string{"google"},
+		Format: FormatSplit,
+		Groups: map[string][]string{"Apple-All": {"icloud", "apple"}},
+	}
+	if err := gs.assembleSplit(task); err != nil {
+		t.Fatalf("assembleSplit got unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(*outputDir, "geosite", splitIndexName))
+	if err != nil {
+		t.Fatalf("failed to read index: %v", err)
+	}
+	var index SplitIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		t.Fatalf("failed to unmarshal index: %v", err)
+	}
+	wantLists := map[string]string{"apple": "apple-all.dat", "cn": "cn.dat", "icloud": "apple-all.dat"}
+	if len(index.Lists) != len(wantLists) {
+		t.Errorf("index lists = %v, want %v", index.Lists, wantLists)
+	}
+	for list, want := range wantLists {
+		if got := index.Lists[list]; got != want {
+			t.Errorf("index lists[%q] = %q, want %q", list, got, want)
+		}
+	}
+	wantFiles := map[string][]string{"apple-all.dat": {"apple", "icloud"}, "cn.dat": {"cn"}}
+	if len(index.Files) != len(wantFiles) {
+		t.Errorf("index has %d files, want %d", len(index.Files), len(wantFiles))
+	}
+	for name, lists := range wantFiles {
+		file, ok := index.Files[name]
+		if !ok {
+			t.Errorf("index has no file %q", name)
+			continue
+		}
+		if !slices.Equal(file.Lists, lists) {
+			t.Errorf("index file %q has lists %v, want %v", name, file.Lists, lists)
+		}
+		got, err := os.ReadFile(filepath.Join(*outputDir, "geosite", name))
+		if err != nil {
+			t.Fatalf("failed to read %q: %v", name, err)
+		}
+		sum := sha256.Sum256(got)
+		if file.Size != int64(len(got)) || file.SHA256 != hex.EncodeToString(sum[:]) {
+			t.Errorf("index file %q = %d %s, want %d %x", name, file.Size, file.SHA256, len(got), sum)
+		}
+		geoSiteList := new(router.GeoSiteList)
+		for _, list := range lists {
+			geoSiteList.Entry = append(geoSiteList.Entry, gs.Sites[gs.SiteIdx[strings.ToUpper(list)]])
+		}
+		want, err := proto.Marshal(geoSiteList)
+		if err != nil {
+			t.Fatalf("proto.Marshal(%q) got unexpected error: %v", name, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("assembleSplit() file %q differs from proto.Marshal", name)
+		}
+	}
+
+	// Files of former runs are removed unless the index lists them
+	dir := filepath.Join(*outputDir, "geosite")
+	for _, name := range []string{"google.dat", "google.dat.xz", "google.dat.xz.sha256sum", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatalf("failed to write %q: %v", name, err)
+		}
+	}
+	if err := gs.assembleSplit(task); err != nil {
+		t.Fatalf("assembleSplit got unexpected error: %v", err)
+	}
+	for name, want := range map[string]bool{"apple-all.dat": true, "cn.dat": true, splitIndexName: true, "notes.txt": true, "google.dat": false, "google.dat.xz": false, "google.dat.xz.sha256sum": false} {
+		if _, err := os.Stat(filepath.Join(dir, name)); (err == nil) != want {
+			t.Errorf("file %q exists = %v, want %v", name, err == nil, want)
+		}
+	}
+
+	for _, groups := range []map[string][]string{
+		{"apple-all": {"google"}},                         // Denied
+		{"apple-all": {"apple"}, "icloud-all": {"apple"}}, // In two groups
+		{"cn": {"apple"}},                                 // Same name as a list
+	} {
+		task.Groups = groups
+		if err := gs.assembleSplit(task); err == nil {
+			t.Errorf("assembleSplit() with groups %v = nil error, want error", groups)
+		}
+	}
+}
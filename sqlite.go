@@ -0,0 +1,186 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/v2fly/domain-list-community/internal/dlc"
+	_ "modernc.org/sqlite"
+)
+
+var sqliteDB = flag.String("sqlitedb", "", "Name of the SQLite database file of all lists, their rules, inclusions and affiliations (empty to skip)")
+
+// sqliteSchema creates the tables of the SQLite database. The rules of a list
+// are its deduplicated rules before polishList, where the redundant ones are
+// trimmed by polishList. The reversed domain of a domain or full type rule has
+// its labels reversed with a trailing dot, e.g. "com.example.", so that the
+// rules under a domain are matched by the index with `reversed GLOB 'com.*'`.
+const sqliteSchema = `
+CREATE TABLE lists (
+	id INTEGER PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE,
+	has_file INTEGER NOT NULL
+);
+CREATE TABLE rules (
+	id INTEGER PRIMARY KEY,
+	list_id INTEGER NOT NULL REFERENCES lists(id),
+	type TEXT NOT NULL,
+	value TEXT NOT NULL,
+	reversed TEXT,
+	redundant INTEGER NOT NULL,
+	file TEXT NOT NULL,
+	line INTEGER NOT NULL
+);
+CREATE TABLE attributes (
+	rule_id INTEGER NOT NULL REFERENCES rules(id),
+	attr TEXT NOT NULL,
+	PRIMARY KEY (rule_id, attr)
+);
+CREATE TABLE inclusions (
+	list_id INTEGER NOT NULL REFERENCES lists(id),
+	source_id INTEGER NOT NULL REFERENCES lists(id),
+	must_attrs TEXT NOT NULL,
+	ban_attrs TEXT NOT NULL
+);
+CREATE TABLE affiliations (
+	list_id INTEGER NOT NULL REFERENCES lists(id),
+	affiliator_id INTEGER NOT NULL REFERENCES lists(id),
+	PRIMARY KEY (list_id, affiliator_id)
+);
+CREATE VIEW final_rules AS SELECT * FROM rules WHERE NOT redundant;
+`
+
+// sqliteIndexes creates the indexes after the rows are inserted, which is
+// faster than updating them for every row.
+const sqliteIndexes = `
+CREATE INDEX rules_list_id ON rules(list_id);
+CREATE INDEX rules_reversed ON rules(reversed);
+CREATE INDEX attributes_attr ON attributes(attr);
+CREATE INDEX inclusions_source_id ON inclusions(source_id);
+`
+
+// writeSQLite writes all the resolved lists into a new SQLite database at the
+// path, replacing the existing file.
+func (p *Processor) writeSQLite(path string) (err error) {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := db.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(path)
+		}
+	}()
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		return fmt.Errorf("failed to create tables: %w", err)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := p.insertSQLite(tx); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(sqliteIndexes); err != nil {
+		return fmt.Errorf("failed to create indexes: %w", err)
+	}
+	return tx.Commit()
+}
+
+// insertSQLite inserts the lists and their rules, inclusions and affiliations
+// in the order of list names, so that the ids are stable.
+func (p *Processor) insertSQLite(tx *sql.Tx) error {
+	plnames := make([]string, 0, len(p.parsedListByName))
+	for plname := range p.parsedListByName {
+		plnames = append(plnames, plname)
+	}
+	slices.Sort(plnames)
+	listIDs := make(map[string]int, len(plnames))
+	for i, plname := range plnames {
+		listIDs[plname] = i + 1
+	}
+
+	insertList, err := tx.Prepare("INSERT INTO lists (id, name, has_file) VALUES (?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	insertRule, err := tx.Prepare("INSERT INTO rules (list_id, type, value, reversed, redundant, file, line) VALUES (?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	insertAttr, err := tx.Prepare("INSERT INTO attributes (rule_id, attr) VALUES (?, ?)")
+	if err != nil {
+		return err
+	}
+	insertInclusion, err := tx.Prepare("INSERT INTO inclusions (list_id, source_id, must_attrs, ban_attrs) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	insertAffiliation, err := tx.Prepare("INSERT INTO affiliations (list_id, affiliator_id) VALUES (?, ?)")
+	if err != nil {
+		return err
+	}
+
+	for _, plname := range plnames {
+		pl := p.parsedListByName[plname]
+		listID := listIDs[plname]
+		if _, err := insertList.Exec(listID, strings.ToLower(plname), pl.HasFile); err != nil {
+			return fmt.Errorf("failed to insert list %q: %w", plname, err)
+		}
+
+		final := make(map[*Entry]bool, len(pl.FinalEntries))
+		for _, entry := range pl.FinalEntries {
+			final[entry] = true
+		}
+		entries := make([]*Entry, 0, len(pl.RoughEntries))
+		for _, entry := range pl.RoughEntries {
+			entries = append(entries, entry)
+		}
+		slices.SortFunc(entries, func(a, b *Entry) int {
+			return strings.Compare(a.Plain, b.Plain)
+		})
+		for _, entry := range entries {
+			var reversed any // NULL for keyword and regexp type rules
+			if entry.Type == dlc.RuleTypeDomain || entry.Type == dlc.RuleTypeFullDomain {
+				reversed = entry.reversedKey()
+			}
+			res, err := insertRule.Exec(listID, entry.Type, entry.Value, reversed, !final[entry], entry.File, entry.Line)
+			if err != nil {
+				return fmt.Errorf("failed to insert rule %q of list %q: %w", entry.Plain, plname, err)
+			}
+			ruleID, err := res.LastInsertId()
+			if err != nil {
+				return err
+			}
+			for _, attr := range entry.Attrs {
+				if _, err := insertAttr.Exec(ruleID, attr); err != nil {
+					return fmt.Errorf("failed to insert attribute %q of rule %q: %w", attr, entry.Plain, err)
+				}
+			}
+		}
+
+		for _, inc := range pl.Inclusions {
+			mustAttrs, banAttrs := strings.Join(inc.MustAttrs, ","), strings.Join(inc.BanAttrs, ",")
+			if _, err := insertInclusion.Exec(listID, listIDs[inc.Source], mustAttrs, banAttrs); err != nil {
+				return fmt.Errorf("failed to insert inclusion %q of list %q: %w", inc.Source, plname, err)
+			}
+		}
+		for _, affiliator := range pl.Affiliators {
+			if _, err := insertAffiliation.Exec(listID, listIDs[affiliator]); err != nil {
+				return fmt.Errorf("failed to insert affiliation %q of list %q: %w", affiliator, plname, err)
+			}
+		}
+	}
+	return nil
+}
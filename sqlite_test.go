@@ -0,0 +1,111 @@
+package main
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestWriteSQLite(t *testing.T) {
+	dataPath := t.TempDir()
+	files := map[string]string{
+		"first":  "domain:example.io @cn\nfull:www.example.io\nkeyword:example\ninclude:second @-ads\n",
+		"second": "domain:example.com\nfull:www.example.com\nfull:ads.example.com @ads &third\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dataPath, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write test data %q: %v", name, err)
+		}
+	}
+	processor := &Processor{parsedListByName: make(map[string]*ParsedList)}
+	if err := processor.loadDataDir(dataPath); err != nil {
+		t.Fatalf("loadDataDir got unexpected error: %v", err)
+	}
+	if err := processor.resolveAll(); err != nil {
+		t.Fatalf("resolveAll got unexpected error: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "dlc.sqlite")
+	if err := os.WriteFile(path, []byte("stale"), 0644); err != nil {
+		t.Fatalf("failed to write stale database: %v", err)
+	}
+	if err := processor.writeSQLite(path); err != nil {
+		t.Fatalf("writeSQLite got unexpected error: %v", err)
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	query := func(q string) []string {
+		t.Helper()
+		rows, err := db.Query(q)
+		if err != nil {
+			t.Fatalf("query %q got unexpected error: %v", q, err)
+		}
+		defer rows.Close()
+		var got []string
+		for rows.Next() {
+			var s string
+			if err := rows.Scan(&s); err != nil {
+				t.Fatalf("failed to scan %q: %v", q, err)
+			}
+			got = append(got, s)
+		}
+		if err := rows.Err(); err != nil {
+			t.Fatalf("query %q got unexpected error: %v", q, err)
+		}
+		return got
+	}
+	testCases := []struct {
+		query string
+		want  []string
+	}{
+		{
+			"SELECT name || ':' || has_file FROM lists ORDER BY id",
+			[]string{"first:1", "second:1", "third:0"},
+		},
+		{
+			"SELECT l.name || ' ' || r.type || ':' || r.value || ' ' || r.redundant || ' ' || r.file || ':' || r.line FROM rules r JOIN lists l ON l.id = r.list_id ORDER BY r.id",
+			[]string{
+				"first domain:example.com 0 second:1",
+				"first domain:example.io 0 first:1",
+				"first full:www.example.com 1 second:2",
+				"first full:www.example.io 1 first:2",
+				"first keyword:example 0 first:3",
+				"second domain:example.com 0 second:1",
+				"second full:ads.example.com 0 second:3",
+				"second full:www.example.com 1 second:2",
+				"third full:ads.example.com 0 second:3",
+			},
+		},
+		{
+			"SELECT DISTINCT l.name FROM final_rules r JOIN lists l ON l.id = r.list_id JOIN attributes a ON a.rule_id = r.id WHERE r.reversed GLOB 'io.*' AND a.attr = 'cn'",
+			[]string{"first"},
+		},
+		{
+			"SELECT value FROM rules WHERE reversed GLOB 'com.example.*' ORDER BY id",
+			[]string{"example.com", "www.example.com", "example.com", "ads.example.com", "www.example.com", "ads.example.com"},
+		},
+		{
+			"SELECT l.name || '<' || s.name || ' @' || i.must_attrs || ' @-' || i.ban_attrs FROM inclusions i JOIN lists l ON l.id = i.list_id JOIN lists s ON s.id = i.source_id",
+			[]string{"first<second @ @-ads"},
+		},
+		{
+			"SELECT l.name || '<' || a.name FROM affiliations f JOIN lists l ON l.id = f.list_id JOIN lists a ON a.id = f.affiliator_id",
+			[]string{"third<second"},
+		},
+	}
+	for _, tc := range testCases {
+		if got := query(tc.query); !slices.Equal(got, tc.want) {
+			t.Errorf("query %q = %q, want %q", tc.query, got, tc.want)
+		}
+	}
+	var reversed sql.NullString
+	if err := db.QueryRow("SELECT reversed FROM rules WHERE type = 'keyword'").Scan(&reversed); err != nil || reversed.Valid {
+		t.Errorf("reversed of keyword rule = %v, %v, want NULL", reversed, err)
+	}
+}
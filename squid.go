@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/v2fly/domain-list-community/internal/dlc"
+)
+
+// writeSquid writes the lists as a Squid `dstdomain` ACL file, where
+// `.example.com` matches example.com and its subdomains. Keyword and regexp
+// rules are left to the `dstdom_regex` file of writeSquidRegex.
+func writeSquid(w io.Writer, lists []*ExportList, _ ExportOptions) error {
+	var dropped []*Entry
+	for _, entry := range stripAttrs(mergeEntries(lists)) {
+		switch entry.Type {
+		case dlc.RuleTypeDomain:
+			fmt.Fprintln(w, "."+entry.Value)
+		case dlc.RuleTypeFullDomain:
+			fmt.Fprintln(w, entry.Value)
+		default:
+			dropped = append(dropped, entry)
+		}
+	}
+	warnDropped("squid", lists, dropped)
+	return nil
+}
+
+// writeSquidRegex writes the keyword and regexp rules of the lists as a Squid
+// `dstdom_regex` ACL file, which is the companion of the `dstdomain` file of
+// writeSquid. The regexps are in POSIX extended syntax.
+func writeSquidRegex(w io.Writer, lists []*ExportList, _ ExportOptions) error {
+	var dropped []*Entry
+	for _, entry := range stripAttrs(mergeEntries(lists)) {
+		expr := entry.Value
+		switch entry.Type {
+		case dlc.RuleTypeKeyword:
+			expr = regexp.QuoteMeta(expr)
+		case dlc.RuleTypeRegexp:
+		default:
+			continue
+		}
+		if posix, ok := posixRegexp(expr); ok {
+			fmt.Fprintln(w, posix)
+		} else {
+			dropped = append(dropped, entry)
+		}
+	}
+	warnDropped("squid-regex", lists, dropped)
+	return nil
+}
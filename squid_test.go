@@ -0,0 +1,31 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteSquid(t *testing.T) {
+	lists := []*ExportList{testExportList(t, "gfw",
+		"domain:example.com",
+		"full:www.example.org @cn",
+		"keyword:tracker",
+		`regexp:^ads\d+\.example\.net$`,
+		`regexp:\bads\.`, // Dropped
+	)}
+	var buf bytes.Buffer
+	if err := writeSquid(&buf, lists, nil); err != nil {
+		t.Fatalf("writeSquid got unexpected error: %v", err)
+	}
+	if got, want := buf.String(), ".example.com\nwww.example.org\n"; got != want {
+		t.Errorf("writeSquid() = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	if err := writeSquidRegex(&buf, lists, nil); err != nil {
+		t.Fatalf("writeSquidRegex got unexpected error: %v", err)
+	}
+	if got, want := buf.String(), "tracker\n^ads[0-9]+\\.example\\.net$\n"; got != want {
+		t.Errorf("writeSquidRegex() = %q, want %q", got, want)
+	}
+}
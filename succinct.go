@@ -0,0 +1,60 @@
+package main
+
+import "unicode/utf8"
+
+// succinctSet is a set of strings in a LOUDS encoded trie, used by the domain
+// matchers of binary rule-set formats. Nodes are numbered in breadth-first
+// order. Each node has its labels in labels, and has a 0 bit per label in
+// labelBitmap followed by a 1 bit as the end of its labels. The bit of a node
+// in leaves is set if a string ends at the node.
+type succinctSet struct {
+	leaves      []uint64
+	labelBitmap []uint64
+	labels      []byte
+}
+
+// newSuccinctSet returns the set of the keys, which must be sorted and
+// deduplicated.
+func newSuccinctSet(keys []string) *succinctSet {
+	ss := new(succinctSet)
+	type span struct{ start, end, col int } // Keys sharing a prefix of length col
+	queue := []span{{0, len(keys), 0}}
+	bitIdx := 0
+	for i := 0; i < len(queue); i++ {
+		node := queue[i]
+		if node.col == len(keys[node.start]) {
+			setBit(&ss.leaves, i)
+			node.start++
+		}
+		for j := node.start; j < node.end; {
+			from := j
+			for ; j < node.end && keys[j][node.col] == keys[from][node.col]; j++ {
+			}
+			queue = append(queue, span{from, j, node.col + 1})
+			ss.labels = append(ss.labels, keys[from][node.col])
+			bitIdx++ // Bit 0 for a label
+		}
+		setBit(&ss.labelBitmap, bitIdx)
+		bitIdx++
+	}
+	return ss
+}
+
+func setBit(bitmap *[]uint64, i int) {
+	for i>>6 >= len(*bitmap) {
+		*bitmap = append(*bitmap, 0)
+	}
+	(*bitmap)[i>>6] |= 1 << (i & 63)
+}
+
+// reverseDomain reverses the domain by runes, so that domains sharing a
+// suffix share a prefix in the set.
+func reverseDomain(domain string) string {
+	b := make([]byte, len(domain))
+	for i := 0; i < len(domain); {
+		r, n := utf8.DecodeRuneInString(domain[i:])
+		i += n
+		utf8.EncodeRune(b[len(domain)-i:], r)
+	}
+	return string(b)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNewSuccinctSet(t *testing.T) {
+	keys := []string{"", "a", "ab", "abc", "abd", "b", "ba", "moc.elpmaxe"}
+	ss := newSuccinctSet(keys)
+	if got := succinctKeys(ss.leaves, ss.labelBitmap, ss.labels); !slices.Equal(got, keys) {
+		t.Errorf("keys of newSuccinctSet(%q) = %q", keys, got)
+	}
+}
+
+func TestReverseDomain(t *testing.T) {
+	testCases := []struct{ domain, want string }{
+		{"", ""},
+		{"example.com", "moc.elpmaxe"},
+		{"\nexample.com", "moc.elpmaxe\n"},
+		{"例子.测试", "试测.子例"},
+	}
+	for _, tc := range testCases {
+		if got := reverseDomain(tc.domain); got != tc.want {
+			t.Errorf("reverseDomain(%q) = %q, want %q", tc.domain, got, tc.want)
+		}
+	}
+}
+
+// succinctKeys decodes the sorted keys of a succinct set.
+func succinctKeys(leaves, labelBitmap []uint64, labels []byte) []string {
+	// The child of the i-th label is the node i+1, as nodes are numbered in
+	// breadth-first order
+	prefixes := []string{""}
+	for node, label, bit := 0, 0, 0; label < len(labels); bit++ {
+		if labelBitmap[bit>>6]&(1<<(bit&63)) != 0 {
+			node++
+			continue
+		}
+		prefixes = append(prefixes, prefixes[node]+string(labels[label]))
+		label++
+	}
+	var keys []string
+	for node, prefix := range prefixes {
+		if node>>6 < len(leaves) && leaves[node>>6]&(1<<(node&63)) != 0 {
+			keys = append(keys, prefix)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
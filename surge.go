@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/v2fly/domain-list-community/internal/dlc"
+)
+
+// SurgeDialect is the rule syntax of a Surge-like client.
+type SurgeDialect struct {
+	Name     string
+	Wildcard bool // Supports DOMAIN-WILDCARD
+	URLRegex bool // Supports URL-REGEX, which only matches HTTP requests or decrypted HTTPS requests
+}
+
+var (
+	DialectSurge        = &SurgeDialect{Name: "surge", Wildcard: true, URLRegex: true}
+	DialectLoon         = &SurgeDialect{Name: "loon", URLRegex: true}
+	DialectShadowrocket = &SurgeDialect{Name: "shadowrocket", Wildcard: true, URLRegex: true}
+	DialectStash        = &SurgeDialect{Name: "stash"}
+)
+
+// rules returns the ruleset lines of the entries. A regexp rule becomes domain
+// rules or wildcard rules if it is exactly equivalent to them, otherwise a
+// URL-REGEX rule if the dialect supports it.
+func (d *SurgeDialect) rules(entries []*Entry) ([]string, []*Entry) {
+	rules := make([]string, 0, len(entries))
+	var dropped []*Entry
+	for _, entry := range entries {
+		switch entry.Type {
+		case dlc.RuleTypeDomain:
+			rules = append(rules, "DOMAIN-SUFFIX,"+entry.Value)
+		case dlc.RuleTypeFullDomain:
+			rules = append(rules, "DOMAIN,"+entry.Value)
+		case dlc.RuleTypeKeyword:
+			rules = append(rules, "DOMAIN-KEYWORD,"+entry.Value)
+		case dlc.RuleTypeRegexp:
+			hr, ok := parseHostRegexp(entry.Value)
+			if !ok {
+				dropped = append(dropped, entry)
+				continue
+			}
+			if pattern, ok := hr.wildcard(); ok && hr.start != anchorNone && hr.end {
+				if !strings.ContainsAny(pattern, "*?") {
+					if hr.start == anchorLabel {
+						rules = append(rules, "DOMAIN-SUFFIX,"+pattern)
+					} else {
+						rules = append(rules, "DOMAIN,"+pattern)
+					}
+					continue
+				}
+				if d.Wildcard {
+					rules = append(rules, "DOMAIN-WILDCARD,"+pattern)
+					if hr.start == anchorLabel {
+						rules = append(rules, "DOMAIN-WILDCARD,*."+pattern)
+					}
+					continue
+				}
+			}
+			// Commas separate the fields of rules
+			if urlRegexp := hr.urlRegexp(); d.URLRegex && !strings.Contains(urlRegexp, ",") {
+				rules = append(rules, "URL-REGEX,"+urlRegexp)
+			} else {
+				dropped = append(dropped, entry)
+			}
+		}
+	}
+	return rules, dropped
+}
+
+// write returns the function writing lists as a ruleset of the dialect.
+func (d *SurgeDialect) write() func(io.Writer, []*ExportList, ExportOptions) error {
+	return func(w io.Writer, lists []*ExportList, _ ExportOptions) error {
+		rules, dropped := d.rules(stripAttrs(mergeEntries(lists)))
+		warnDropped(d.Name, lists, dropped)
+		for _, rule := range rules {
+			fmt.Fprintln(w, rule)
+		}
+		return nil
+	}
+}
+
+// writeDomainSet writes the lists as a Surge DOMAIN-SET, where `.example.com`
+// matches example.com and its subdomains.
+func writeDomainSet(w io.Writer, lists []*ExportList, _ ExportOptions) error {
+	var dropped []*Entry
+	for _, entry := range stripAttrs(mergeEntries(lists)) {
+		switch entry.Type {
+		case dlc.RuleTypeDomain:
+			fmt.Fprintln(w, "."+entry.Value)
+		case dlc.RuleTypeFullDomain:
+			fmt.Fprintln(w, entry.Value)
+		default:
+			dropped = append(dropped, entry)
+		}
+	}
+	warnDropped("domainset", lists, dropped)
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSurgeDialects(t *testing.T) {
+	list := testExportList(t, "test",
+		"domain:example.com @ads",
+		"full:www.example.org",
+		"keyword:tracker",
+		`regexp:(^|\.)example\.net$`,
+		`regexp:^cdn.+\.example\.edu$`,
+		`regexp:^ads\d\.example\.edu$`,
+		`regexp:^x{1,3}\.example\.edu$`, // Commas separate the fields of rules
+	)
+	common := "DOMAIN-SUFFIX,example.com\nDOMAIN,www.example.org\nDOMAIN-KEYWORD,tracker\n"
+	testCases := []struct {
+		format string
+		want   string
+	}{
+		{"surge", common +
+			"DOMAIN-SUFFIX,example.net\n" +
+			"URL-REGEX,^https?://ads[0-9]\\.example\\.edu(?::\\d+)?(?:[/?#]|$)\n" +
+			"DOMAIN-WILDCARD,cdn?*.example.edu\n"},
+		{"loon", common +
+			"DOMAIN-SUFFIX,example.net\n" +
+			"URL-REGEX,^https?://ads[0-9]\\.example\\.edu(?::\\d+)?(?:[/?#]|$)\n" +
+			"URL-REGEX,^https?://cdn[\\-\\.0-9A-Z_a-z]+\\.example\\.edu(?::\\d+)?(?:[/?#]|$)\n"},
+		{"stash", common + "DOMAIN-SUFFIX,example.net\n"},
+		{"domainset", ".example.com\nwww.example.org\n"},
+	}
+	for _, tc := range testCases {
+		var buf bytes.Buffer
+		if err := listFormats[tc.format].Write(&buf, []*ExportList{list}, nil); err != nil {
+			t.Errorf("%s got unexpected error: %v", tc.format, err)
+			continue
+		}
+		got := buf.String()
+		if got != tc.want {
+			t.Errorf("%s = %q, want %q", tc.format, got, tc.want)
+		}
+	}
+}
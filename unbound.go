@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+const defaultUnboundZoneType = "always_nxdomain"
+
+// unboundZoneTypes are the types of Unbound local zones which need no local
+// data.
+var unboundZoneTypes = []string{
+	"deny", "refuse", "static", "transparent", "typetransparent", "inform", "inform_deny",
+	"always_transparent", "block_a", "block_aaaa", "always_refuse", "always_nxdomain",
+	"always_nodata", "always_deny", "always_null",
+}
+
+// writeUnbound writes the lists as Unbound config, where every domain is a
+// local zone of the `zone` type of its list, or a forward zone to the
+// upstreams of the `forward` option, like `1.1.1.1@53 8.8.8.8`, if it is set.
+// Zones always contain the subdomains of their domains.
+func writeUnbound(w io.Writer, lists []*ExportList, opts ExportOptions) error {
+	var localZones, forwardZones []string
+	seen := make(map[string]bool)
+	for _, list := range lists {
+		zoneType := opts.getFor("zone", list.Name, defaultUnboundZoneType)
+		if !slices.Contains(unboundZoneTypes, zoneType) {
+			return fmt.Errorf("invalid local zone type %q of list %q", zoneType, list.Name)
+		}
+		upstreams := strings.Fields(opts.getFor("forward", list.Name, ""))
+		for _, upstream := range upstreams {
+			if strings.ContainsAny(upstream, `"#`) {
+				return fmt.Errorf("invalid forward address %q of list %q", upstream, list.Name)
+			}
+		}
+
+		for _, domain := range suffixDomains("unbound", []*ExportList{list}, false) {
+			if seen[domain] { // The first list of a domain wins
+				continue
+			}
+			seen[domain] = true
+			if len(upstreams) == 0 {
+				localZones = append(localZones, fmt.Sprintf("  local-zone: %q %s\n", domain+".", zoneType))
+				continue
+			}
+			var sb strings.Builder
+			fmt.Fprintf(&sb, "forward-zone:\n  name: %q\n", domain+".")
+			for _, upstream := range upstreams {
+				fmt.Fprintf(&sb, "  forward-addr: %s\n", upstream)
+			}
+			forwardZones = append(forwardZones, sb.String())
+		}
+	}
+
+	if len(localZones) != 0 {
+		fmt.Fprintln(w, "server:")
+		for _, zone := range localZones {
+			io.WriteString(w, zone)
+		}
+	}
+	for _, zone := range forwardZones {
+		io.WriteString(w, zone)
+	}
+	return nil
+}
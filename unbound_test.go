@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteUnbound(t *testing.T) {
+	lists := []*ExportList{
+		testExportList(t, "category-ads",
+			"domain:example.com",
+			"full:ads.example.org", // Approximated
+			"keyword:tracker",      // Dropped
+		),
+		testExportList(t, "cn",
+			"domain:example.cn",
+			"domain:example.com", // Duplicated
+		),
+	}
+	var buf bytes.Buffer
+	if err := writeUnbound(&buf, lists, ExportOptions{"forward:cn": "114.114.114.114 223.5.5.5@53"}); err != nil {
+		t.Fatalf("writeUnbound got unexpected error: %v", err)
+	}
+	want := `server:
+  local-zone: "example.com." always_nxdomain
+  local-zone: "ads.example.org." always_nxdomain
+forward-zone:
+  name: "example.cn."
+  forward-addr: 114.114.114.114
+  forward-addr: 223.5.5.5@53
+`
+	if got := buf.String(); got != want {
+		t.Errorf("writeUnbound() = %s, want %s", got, want)
+	}
+
+	for _, opts := range []ExportOptions{
+		{"zone": "redirect"},
+		{"forward": `1.1.1.1"`},
+	} {
+		if err := writeUnbound(new(bytes.Buffer), lists, opts); err == nil {
+			t.Errorf("writeUnbound(%v) = nil error, want error", opts)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var (
+	strictMode = flag.Bool("strict", false, "Treat all warnings as errors, unless they are configured by 'warnings' option")
+	warnLevels = flag.String("warnings", "", "Levels of warning codes, separated by ',' comma, e.g. 'empty-list=ignore,denylist-missing=error'")
+)
+
+// Codes of warnings, which are stable so that warnings can be selectively
+// suppressed or turned into errors
+const (
+	WarnEmptyList        string = "empty-list"        // A list has no rule after resolving
+	WarnDenylistMissing  string = "denylist-missing"  // A list to deny does not exist
+	WarnDenylistEmpty    string = "denylist-empty"    // A denylist task denies nothing
+	WarnExportMissing    string = "export-missing"    // A list to export does not exist or is empty
+	WarnExportLossy      string = "export-lossy"      // Rules are dropped as the export format cannot express them
+	WarnOverlapUnchecked string = "overlap-unchecked" // Rules of lists which must be disjoint are unchecked for overlaps
+)
+
+var warnCodes = []string{WarnEmptyList, WarnDenylistMissing, WarnDenylistEmpty, WarnExportMissing, WarnExportLossy, WarnOverlapUnchecked}
+
+// Levels of warnings
+const (
+	LevelIgnore string = "ignore"
+	LevelWarn   string = "warn"
+	LevelError  string = "error"
+)
+
+type Warner struct {
+	DefaultLevel string
+	Levels       map[string]string // Levels by warning code
+	ErrorCount   int               // Number of warnings treated as errors
+}
+
+// warner reports the warnings of the current run
+var warner = &Warner{DefaultLevel: LevelWarn}
+
+// newWarner parses the levels of warning codes like `empty-list=ignore`.
+func newWarner(strict bool, levels string) (*Warner, error) {
+	w := &Warner{DefaultLevel: LevelWarn, Levels: make(map[string]string)}
+	if strict {
+		w.DefaultLevel = LevelError
+	}
+	for rawLevel := range strings.SplitSeq(levels, ",") {
+		rawLevel = strings.TrimSpace(rawLevel)
+		if rawLevel == "" {
+			continue
+		}
+		code, level, ok := strings.Cut(rawLevel, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid warning level: %q", rawLevel)
+		}
+		code, level = strings.TrimSpace(code), strings.ToLower(strings.TrimSpace(level))
+		if !slices.Contains(warnCodes, code) {
+			return nil, fmt.Errorf("unknown warning code: %q", code)
+		}
+		switch level {
+		case LevelIgnore, LevelWarn, LevelError:
+			w.Levels[code] = level
+		default:
+			return nil, fmt.Errorf("invalid level %q of warning %q", level, code)
+		}
+	}
+	return w, nil
+}
+
+// warnf reports a warning of the code according to its level.
+func (w *Warner) warnf(code string, format string, args ...any) {
+	level, ok := w.Levels[code]
+	if !ok {
+		level = w.DefaultLevel
+	}
+	switch level {
+	case LevelIgnore:
+		return
+	case LevelError:
+		w.ErrorCount++
+		fmt.Printf("[Error] "+format+" [%s]\n", append(args, code)...)
+	default:
+		fmt.Printf("[Warn] "+format+" [%s]\n", append(args, code)...)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+func TestNewWarner(t *testing.T) {
+	testCases := []struct {
+		name       string
+		strict     bool
+		levels     string
+		wantErrors int
+		wantErr    bool
+	}{
+		{name: "default", wantErrors: 0},
+		{name: "strict", strict: true, wantErrors: 2},
+		{name: "strict with allowed empty list", strict: true, levels: "empty-list=ignore", wantErrors: 1},
+		{name: "selective error", levels: " denylist-missing = Error ", wantErrors: 1},
+		{name: "unknown code", levels: "empty-lists=ignore", wantErr: true},
+		{name: "invalid level", levels: "empty-list=fatal", wantErr: true},
+		{name: "missing level", levels: "empty-list", wantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w, err := newWarner(tc.strict, tc.levels)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("newWarner(%v, %q) = %+v, want error", tc.strict, tc.levels, w)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newWarner(%v, %q) got unexpected error: %v", tc.strict, tc.levels, err)
+			}
+			w.warnf(WarnEmptyList, "ignore empty list %q", "TEST")
+			w.warnf(WarnDenylistMissing, "list %q not found in denylist task %q", "TEST", "test.dat")
+			if w.ErrorCount != tc.wantErrors {
+				t.Errorf("newWarner(%v, %q) reported %d error(s), want %d", tc.strict, tc.levels, w.ErrorCount, tc.wantErrors)
+			}
+		})
+	}
+}